@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
 	runner "github.com/alexanderchan/alex-runner/internal"
 )
@@ -26,6 +29,20 @@ func main() {
 		generateCompletion string
 		pinScript          string
 		unpinScript        string
+		showGraph          bool
+		graphFormat        string
+		dbPath             string
+		mergeFrom          string
+		runGC              bool
+		migrateRenames     bool
+		dryRun             bool
+		exportHistory      bool
+		importFile         string
+		mergeStrategy      string
+		pathRewrites       []runner.PathRewrite
+		globalMode         bool
+		pickProject        bool
+		height             string
 	)
 
 	// Split arguments at -- to separate our flags from script arguments
@@ -50,12 +67,39 @@ func main() {
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.BoolVar(&usePackageJSON, "use-package-json", false, "Only show package.json scripts (ignore Makefile)")
 	flag.BoolVar(&useMakefile, "use-makefile", false, "Only show Makefile targets (ignore package.json)")
+	flag.BoolVar(&globalMode, "global", false, "Search scripts of every project with usage history and run them in their own directory")
+	flag.BoolVar(&pickProject, "projects", false, "Pick a project with usage history: print its path, or show its scripts")
+	flag.Func("height", "Show the selector below the prompt with this height (lines or N%), or full screen (full)", func(value string) error {
+		if _, err := runner.ParseSelectorHeight(value); err != nil {
+			return err
+		}
+		height = value
+		return nil
+	})
 	flag.BoolVar(&noCache, "no-cache", false, "Re-detect package manager instead of using cached value")
+	flag.BoolVar(&showGraph, "graph", false, "Show what a script triggers (Makefile prerequisites, pre/post hooks, nested runs)")
+	flag.StringVar(&graphFormat, "graph-format", "tree", "Output format for --graph (tree|dot|mermaid)")
+	flag.StringVar(&dbPath, "db", "", "Path to the usage history database (overrides $ALEX_RUNNER_DB)")
+	flag.BoolVar(&runGC, "gc", false, "Remove history for missing directories and deleted scripts, apply retention, and vacuum")
+	flag.BoolVar(&migrateRenames, "migrate-renames", false, "Carry history over to scripts that were renamed (same command, new name)")
+	flag.BoolVar(&dryRun, "dry-run", false, "With --gc or --migrate-renames: show what would change without changing anything")
+	flag.StringVar(&mergeFrom, "merge-history", "", "Merge usage history recorded at another path into this project")
+	flag.BoolVar(&exportHistory, "export", false, "Export usage history, pins, runs and caches (to the file given as argument, or stdout)")
+	flag.StringVar(&importFile, "import", "", "Import usage history from an --export file")
+	flag.StringVar(&mergeStrategy, "merge-strategy", "sum", "How --import combines with existing history (sum|max|overwrite)")
+	flag.Func("rewrite-path", "Rewrite directories on --import (old=new, repeatable)", func(value string) error {
+		rewrite, err := runner.ParsePathRewrite(value)
+		if err != nil {
+			return err
+		}
+		pathRewrites = append(pathRewrites, rewrite)
+		return nil
+	})
 	flag.Parse()
 
 	// If no flags provided but positional args exist, join all args as search term
 	// Allow search term with -l flag for "I'm feeling lucky" with search
-	if searchTerm == "" && !listScripts && !resetDir && !resetAll && len(flag.Args()) > 0 {
+	if searchTerm == "" && !listScripts && !resetDir && !resetAll && !exportHistory && len(flag.Args()) > 0 {
 		searchTerm = strings.Join(flag.Args(), " ")
 	}
 
@@ -98,14 +142,43 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load user config (missing file means defaults)
+	cfg, err := runner.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := runner.ApplyTheme(cfg); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if height != "" {
+		cfg.Height = height
+	} else if _, err := runner.ParseSelectorHeight(cfg.Height); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		cfg.Height = ""
+	}
+
 	// Initialize database
-	db, err := runner.InitDatabase()
+	db, err := runner.InitDatabaseFrom(dbPath)
 	if err != nil {
 		fmt.Printf("Error: failed to initialize database: %v\n", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
+	// History is keyed by project (git remote + path in repo) so clones, renamed
+	// folders and worktrees share it. Shell completion runs on every TAB, so it reuses
+	// the project registered for this directory instead of running git.
+	project, cached := runner.Project{}, false
+	if listNames {
+		project, cached = db.CachedProject(absPath)
+	}
+	if !cached {
+		project = runner.ResolveProject(absPath)
+		if _, err := db.RegisterProject(project); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
 	// Handle reset flags
 	if resetAll {
 		if err := db.ResetAll(); err != nil {
@@ -117,7 +190,7 @@ func main() {
 	}
 
 	if resetDir {
-		if err := db.ResetDirectory(absPath); err != nil {
+		if err := db.ResetDirectory(project.Key); err != nil {
 			fmt.Printf("Error: failed to reset directory history: %v\n", err)
 			os.Exit(1)
 		}
@@ -125,6 +198,114 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle gc flag
+	if runGC {
+		report, err := db.GC(gcOptions(cfg, dryRun))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		printGCReport(report, dryRun)
+		os.Exit(0)
+	}
+
+	// Handle merge-history flag
+	if mergeFrom != "" {
+		oldPath, err := filepath.Abs(mergeFrom)
+		if err != nil {
+			fmt.Printf("Error: failed to get absolute path: %v\n", err)
+			os.Exit(1)
+		}
+
+		keys, err := db.HistoryKeysForDirectory(oldPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var total runner.ImportReport
+		for _, key := range keys {
+			report, err := db.MergeHistory(key, project.Key)
+			if err != nil {
+				fmt.Printf("Error: failed to merge history: %v\n", err)
+				os.Exit(1)
+			}
+			total.Usage += report.Usage
+			total.Runs += report.Runs
+			total.MakeVariables += report.MakeVariables
+		}
+		if total.Usage == 0 && total.Runs == 0 && total.MakeVariables == 0 {
+			fmt.Printf("Error: no usage history found for %s\n", oldPath)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Merged %d scripts and %d runs from %s into %s\n", total.Usage, total.Runs, oldPath, absPath)
+		os.Exit(0)
+	}
+
+	// Handle export flag (file from the first positional argument, stdout otherwise)
+	if exportHistory {
+		data, err := db.Export()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(flag.Args()) == 0 {
+			if err := runner.WriteExport(os.Stdout, data, runner.ExportJSON); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+
+		exportPath := flag.Arg(0)
+		file, err := os.Create(exportPath)
+		if err != nil {
+			fmt.Printf("Error: failed to create export file: %v\n", err)
+			os.Exit(1)
+		}
+		err = runner.WriteExport(file, data, runner.ExportFormatForPath(exportPath))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Exported %d scripts and %d runs to %s\n", len(data.Usage), len(data.Runs), exportPath)
+		os.Exit(0)
+	}
+
+	// Handle import flag
+	if importFile != "" {
+		strategy, err := runner.ParseMergeStrategy(mergeStrategy)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		file, err := os.Open(importFile)
+		if err != nil {
+			fmt.Printf("Error: failed to open import file: %v\n", err)
+			os.Exit(1)
+		}
+		data, err := runner.ReadExport(file)
+		file.Close()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		report, err := db.Import(data, runner.ImportOptions{Strategy: strategy, Rewrites: pathRewrites})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Imported %d scripts, %d runs, %d Makefile variables and %d cache entries (%s)\n",
+			report.Usage, report.Runs, report.MakeVariables, report.PackageManagers, strategy)
+		os.Exit(0)
+	}
+
 	// Handle pin flag
 	if pinScript != "" {
 		// Need to load scripts first to find the source
@@ -152,8 +333,14 @@ func main() {
 		// Load package.json scripts if exists
 		if hasPackageJSON {
 			pkg, err := runner.ReadPackageJSON(absPath)
-			if err == nil {
-				pkgScripts := runner.GetScripts(pkg)
+			if err == nil || errors.Is(err, runner.ErrNoScripts) {
+				var pkgScripts []runner.NPMScript
+				if pkg != nil {
+					pkgScripts = runner.GetScripts(pkg)
+				}
+				if cfg.Workspaces {
+					pkgScripts = append(pkgScripts, runner.LoadWorkspaceScripts(absPath)...)
+				}
 				packageManager := runner.DetectPackageManager(absPath)
 				for _, script := range pkgScripts {
 					if script.Name == pinScript {
@@ -169,7 +356,7 @@ func main() {
 			os.Exit(1)
 		} else if len(availableScripts) == 1 {
 			// Only one match, pin it
-			if err := db.PinScript(absPath, pinScript, availableScripts[0].Source); err != nil {
+			if err := db.PinScript(project.Key, pinScript, availableScripts[0].Source); err != nil {
 				fmt.Printf("Error: failed to pin script: %v\n", err)
 				os.Exit(1)
 			}
@@ -188,7 +375,7 @@ func main() {
 			if choice == "all" {
 				// Pin all matches
 				for _, script := range availableScripts {
-					if err := db.PinScript(absPath, pinScript, script.Source); err != nil {
+					if err := db.PinScript(project.Key, pinScript, script.Source); err != nil {
 						fmt.Printf("Warning: failed to pin %s (%s): %v\n", pinScript, script.Source, err)
 					} else {
 						fmt.Printf("📌 Pinned script '%s' (%s)\n", pinScript, script.Source)
@@ -202,7 +389,7 @@ func main() {
 					os.Exit(1)
 				}
 				selected := availableScripts[idx-1]
-				if err := db.PinScript(absPath, pinScript, selected.Source); err != nil {
+				if err := db.PinScript(project.Key, pinScript, selected.Source); err != nil {
 					fmt.Printf("Error: failed to pin script: %v\n", err)
 					os.Exit(1)
 				}
@@ -215,7 +402,7 @@ func main() {
 	// Handle unpin flag
 	if unpinScript != "" {
 		// Find all pinned scripts with this name
-		found, err := db.FindScriptsByName(absPath, unpinScript)
+		found, err := db.FindScriptsByName(project.Key, unpinScript)
 		if err != nil {
 			fmt.Printf("Error: failed to find script: %v\n", err)
 			os.Exit(1)
@@ -234,7 +421,7 @@ func main() {
 			os.Exit(1)
 		} else if len(pinnedScripts) == 1 {
 			// Only one pinned match, unpin it
-			if err := db.UnpinScript(absPath, unpinScript, pinnedScripts[0].Source); err != nil {
+			if err := db.UnpinScript(project.Key, unpinScript, pinnedScripts[0].Source); err != nil {
 				fmt.Printf("Error: failed to unpin script: %v\n", err)
 				os.Exit(1)
 			}
@@ -253,7 +440,7 @@ func main() {
 			if choice == "all" {
 				// Unpin all matches
 				for _, script := range pinnedScripts {
-					if err := db.UnpinScript(absPath, unpinScript, script.Source); err != nil {
+					if err := db.UnpinScript(project.Key, unpinScript, script.Source); err != nil {
 						fmt.Printf("Warning: failed to unpin %s (%s): %v\n", unpinScript, script.Source, err)
 					} else {
 						fmt.Printf("✓ Unpinned script '%s' (%s)\n", unpinScript, script.Source)
@@ -267,7 +454,7 @@ func main() {
 					os.Exit(1)
 				}
 				selected := pinnedScripts[idx-1]
-				if err := db.UnpinScript(absPath, unpinScript, selected.Source); err != nil {
+				if err := db.UnpinScript(project.Key, unpinScript, selected.Source); err != nil {
 					fmt.Printf("Error: failed to unpin script: %v\n", err)
 					os.Exit(1)
 				}
@@ -277,72 +464,147 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Determine which sources to use
-	var scripts []runner.NPMScript
+	// If both flags are set, show error
+	if usePackageJSON && useMakefile {
+		fmt.Println("Error: Cannot use both --use-package-json and --use-makefile")
+		os.Exit(1)
+	}
 
-	hasMakefile := runner.MakefileExists(absPath)
-	hasPackageJSON := runner.PackageJSONExists(absPath)
+	strategy, err := runner.FrecencyStrategyFromConfig(cfg)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
 
-	// Detect package manager with caching
-	var packageManager string
-	if !noCache {
-		// Try to get from cache first
-		cachedPM, err := db.GetCachedPackageManager(absPath)
+	keyMap, err := runner.KeyMapFromConfig(cfg)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	// Handle global flag: scripts of every project with history, run in their own directory
+	if globalMode {
+		scoredScripts, err := db.GlobalScripts(runner.Ranker{Strategy: strategy}, runner.ScriptSources{
+			Makefile:    !usePackageJSON,
+			PackageJSON: !useMakefile,
+			Workspaces:  cfg.Workspaces,
+		})
 		if err != nil {
-			fmt.Printf("Warning: failed to get cached package manager: %v\n", err)
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-		if cachedPM != "" {
-			packageManager = cachedPM
+		if len(scoredScripts) == 0 {
+			fmt.Println("Error: no projects with usage history found")
+			os.Exit(1)
 		}
-	}
 
-	// If no cache or --no-cache flag, detect fresh
-	if packageManager == "" {
-		packageManager = runner.DetectPackageManager(absPath)
-		// Cache the detected package manager
-		if err := db.SetCachedPackageManager(absPath, packageManager); err != nil {
-			fmt.Printf("Warning: failed to cache package manager: %v\n", err)
+		var selectedScript *runner.ScoredScript
+		interactive := false
+		if useLast {
+			if searchTerm != "" {
+				selectedScript = bestMatch(scoredScripts, searchTerm)
+			} else if selectedScript = runner.GetMostFrecent(scoredScripts); selectedScript == nil {
+				fmt.Println("Error: no script usage history found")
+				os.Exit(1)
+			}
+		} else {
+			result, err := runner.ShowScriptSelectionWithFilter(runner.SelectorParams{
+				Scripts:       scoredScripts,
+				InitialFilter: searchTerm,
+				DB:            db,
+				Config:        cfg,
+				Args:          scriptArgs,
+				Keys:          &keyMap,
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			selectedScript, scriptArgs = result.Script, result.Args
+			interactive = true
+		}
+		if selectedScript == nil {
+			os.Exit(0)
 		}
-	}
-
-	// Determine which sources to load based on flags and what's available
-	loadMakefile := hasMakefile && !usePackageJSON
-	loadPackageJSON := hasPackageJSON && !useMakefile
 
-	// If both flags are set, show error
-	if usePackageJSON && useMakefile {
-		fmt.Println("Error: Cannot use both --use-package-json and --use-makefile")
-		os.Exit(1)
+		project := *selectedScript.Project
+		runSelectedScript(runRequest{
+			db:          db,
+			cfg:         cfg,
+			strategy:    strategy,
+			project:     project,
+			script:      selectedScript,
+			args:        scriptArgs,
+			interactive: interactive,
+			workContext: runner.DetectWorkContext(project.Directory),
+		})
+		return
 	}
 
-	// Load Makefile targets if needed
-	if loadMakefile {
-		makeTargets, err := runner.ReadMakefile(absPath)
+	// Handle projects flag: print the chosen project's path when captured, as in
+	// cd "$(alex-runner --projects)", or continue with its scripts in a terminal
+	if pickProject {
+		summaries, err := db.ProjectSummaries(runner.Ranker{Strategy: strategy}, runner.ScriptSources{Makefile: true, PackageJSON: true, Workspaces: cfg.Workspaces})
 		if err != nil {
-			fmt.Printf("Error reading Makefile: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		for _, target := range makeTargets {
-			scripts = append(scripts, runner.NPMScript{
-				Name:    target.Name,
-				Command: target.Command,
-				Source:  "make",
-			})
+		if len(summaries) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no projects with usage history found")
+			os.Exit(1)
 		}
-	}
 
-	// Load package.json scripts if needed
-	if loadPackageJSON {
-		pkg, err := runner.ReadPackageJSON(absPath)
-		if err != nil {
-			fmt.Printf("Error reading package.json: %v\n", err)
+		var chosen *runner.ProjectSummary
+		if useLast {
+			matches := runner.SearchProjects(summaries, searchTerm)
+			if len(matches) == 0 {
+				fmt.Fprintf(os.Stderr, "No projects matching '%s' found\n", searchTerm)
+				os.Exit(1)
+			}
+			chosen = &matches[0]
+		} else if chosen, err = runner.ShowProjectPicker(runner.ProjectPickerParams{
+			Projects:      summaries,
+			InitialFilter: searchTerm,
+			Config:        cfg,
+			Keys:          &keyMap,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		pkgScripts := runner.GetScripts(pkg)
-		for i := range pkgScripts {
-			pkgScripts[i].Source = packageManager
+		if chosen == nil {
+			os.Exit(1) // Nothing to cd into
+		}
+
+		if !runner.StdoutIsTerminal() {
+			fmt.Println(chosen.Project.Directory)
+			os.Exit(0)
+		}
+
+		// Show the chosen project's scripts as if alex-runner was started there
+		if err := os.Chdir(chosen.Project.Directory); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-		scripts = append(scripts, pkgScripts...)
+		absPath, project = chosen.Project.Directory, chosen.Project
+		searchTerm, useLast = "", false
+		fmt.Printf("📁 %s\n", runner.ShortenHome(absPath))
+	}
+
+	// Detect package manager with caching
+	packageManager, err := db.PackageManagerFor(absPath, !noCache)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	// Load Makefile targets and package.json scripts
+	// Aggregate targets without a recipe are kept for the dependency graph only
+	scripts, allTargets, err := runner.LoadScripts(absPath, runner.ScriptSources{
+		Makefile:       !usePackageJSON,
+		PackageJSON:    !useMakefile,
+		PackageManager: packageManager,
+		Workspaces:     cfg.Workspaces,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Error if no scripts found
@@ -351,15 +613,122 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Build the dependency graph (Makefile prerequisites, npm hooks, nested runs)
+	// Workspace packages run their scripts in their own directory, so they are left out
+	var pkgScripts []runner.NPMScript
+	for _, script := range scripts {
+		if script.Source != "make" && runner.ScriptWorkspace(script) == "" {
+			pkgScripts = append(pkgScripts, script)
+		}
+	}
+	graph := runner.BuildDependencyGraph(allTargets, pkgScripts)
+
+	// Handle graph flag
+	if showGraph {
+		roots := graph.Roots()
+		if searchTerm != "" {
+			roots = graph.Find(searchTerm)
+			if len(roots) == 0 {
+				fmt.Printf("Error: script '%s' not found\n", searchTerm)
+				os.Exit(1)
+			}
+		}
+
+		switch strings.ToLower(graphFormat) {
+		case "tree":
+			fmt.Print(runner.RenderGraphTree(roots))
+		case "dot":
+			fmt.Print(runner.RenderGraphDOT(roots))
+		case "mermaid":
+			fmt.Print(runner.RenderGraphMermaid(roots))
+		default:
+			fmt.Printf("Error: unsupported graph format '%s' (use tree, dot or mermaid)\n", graphFormat)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Handle migrate-renames flag
+	if migrateRenames {
+		renames, err := db.DetectRenames(project.Key, scripts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(renames) == 0 {
+			fmt.Println("No renamed scripts found")
+			os.Exit(0)
+		}
+		for _, rename := range renames {
+			if dryRun {
+				fmt.Printf("Would migrate %s (%d runs)\n", rename, rename.Old.UseCount)
+				continue
+			}
+			if err := db.MigrateRename(project.Key, rename); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Migrated %s (%d runs)\n", rename, rename.Old.UseCount)
+		}
+		os.Exit(0)
+	}
+
+	// Offer to carry history over to renamed scripts before showing the selector
+	if !useLast && !listScripts && !listNames {
+		renames, err := db.DetectRenames(project.Key, scripts)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		for _, rename := range renames {
+			confirmed, err := runner.ConfirmRename(rename)
+			if errors.Is(err, runner.ErrPromptCancelled) {
+				os.Exit(0)
+			}
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+				break
+			}
+
+			if confirmed {
+				err = db.MigrateRename(project.Key, rename)
+			} else {
+				err = db.DeclineRename(project.Key, rename)
+			}
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	}
+
 	// Get usage stats
-	usageStats, err := db.GetUsageStats(absPath)
+	usageStats, err := db.GetUsageStats(project.Key)
 	if err != nil {
 		fmt.Printf("Error: failed to get usage stats: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Score and sort scripts
-	scoredScripts := runner.ScoreScripts(scripts, usageStats)
+	ranker := runner.Ranker{Strategy: strategy}
+
+	// Boost scripts used before on this branch or with similar files changed. Shell
+	// completion skips this to stay fast on every TAB.
+	var workContext runner.WorkContext
+	var recentRuns []runner.ScriptRun
+	if !listNames {
+		workContext = runner.DetectWorkContext(absPath)
+		if recentRuns, err = db.GetProjectRuns(project.Key, runner.RunHistoryLimit); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		ranker.Context = workContext
+		ranker.Runs = recentRuns
+	}
+	scoredScripts := ranker.ScoreScripts(scripts, usageStats)
+
+	// Predict the next script from what usually follows the last run and time-of-day habits
+	var suggestion *runner.Suggestion
+	if cfg.SuggestNext {
+		suggestion = runner.SuggestNext(scoredScripts, recentRuns, time.Now())
+	}
 
 	// Handle list flag
 	if listScripts {
@@ -390,49 +759,61 @@ func main() {
 		os.Exit(0)
 	}
 
+	selectorParams := runner.SelectorParams{
+		Scripts:    scoredScripts,
+		DB:         db,
+		Directory:  project.Key,
+		Config:     cfg,
+		Graph:      graph,
+		Args:       scriptArgs,
+		Suggestion: suggestion,
+		Keys:       &keyMap,
+	}
+
 	var selectedScript *runner.ScoredScript
+	interactive := false // Whether the script was picked in the selector (vs. -l)
 
 	// Handle search term with -l flag: "I'm feeling lucky" with search
 	if searchTerm != "" && useLast {
-		searchResults := runner.SearchScripts(scoredScripts, searchTerm)
-		if len(searchResults) == 0 {
-			fmt.Printf("No scripts matching '%s' found\n", searchTerm)
-			os.Exit(1)
-		}
-		// Run first match immediately
-		selectedScript = &searchResults[0]
-		fmt.Printf("Selected: %s → %s\n", selectedScript.Script.Name, selectedScript.Script.Command)
+		selectedScript = bestMatch(scoredScripts, searchTerm)
 	} else if searchTerm != "" {
 		// Search without -l: show custom selector with editable filter pre-populated with search term
 		// Use all scripts (not pre-filtered) so user can edit and see different results
-		selected, err := runner.ShowScriptSelectionWithDB(scoredScripts, searchTerm, db, absPath)
+		selectorParams.InitialFilter = searchTerm
+		result, err := runner.ShowScriptSelectionWithFilter(selectorParams)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		selectedScript = selected
+		selectedScript, scriptArgs = result.Script, result.Args
+		interactive = true
 	} else if useLast {
-		// -l without search: use most frecent
-		mostFrecent := runner.GetMostFrecent(scoredScripts)
-		if mostFrecent == nil {
+		// -l without search: a pinned script, the confident suggestion, or the most frecent
+		pick, suggested := runner.PickLast(scoredScripts, suggestion)
+		if suggested {
+			selectedScript = pick
+			fmt.Printf("Suggested: %s (%s)\n", selectedScript.Script.Name, suggestion.Reason)
+		} else if pick == nil {
 			fmt.Println("No script usage history found. Please select a script:")
-			selected, err := runner.ShowScriptSelectionWithDB(scoredScripts, "", db, absPath)
+			result, err := runner.ShowScriptSelectionWithFilter(selectorParams)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
-			selectedScript = selected
+			selectedScript, scriptArgs = result.Script, result.Args
+			interactive = true
 		} else {
-			selectedScript = mostFrecent
+			selectedScript = pick
 		}
 	} else {
 		// Default behavior: show interactive selection
-		selected, err := runner.ShowScriptSelectionWithDB(scoredScripts, "", db, absPath)
+		result, err := runner.ShowScriptSelectionWithFilter(selectorParams)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		selectedScript = selected
+		selectedScript, scriptArgs = result.Script, result.Args
+		interactive = true
 	}
 
 	if selectedScript == nil {
@@ -440,54 +821,199 @@ func main() {
 		os.Exit(0)
 	}
 
+	runSelectedScript(runRequest{
+		db:          db,
+		cfg:         cfg,
+		strategy:    strategy,
+		project:     project,
+		script:      selectedScript,
+		args:        scriptArgs,
+		interactive: interactive,
+		workContext: workContext,
+	})
+}
+
+// runRequest is a script picked to run and the project it belongs to
+type runRequest struct {
+	db          *runner.Database
+	cfg         *runner.Config
+	strategy    runner.FrecencyStrategy
+	project     runner.Project
+	script      *runner.ScoredScript
+	args        []string
+	interactive bool // Picked in the selector (vs. -l), so prompts are expected
+	workContext runner.WorkContext
+}
+
+// runSelectedScript asks for Makefile variables if needed, records the usage, runs the
+// script in its project directory and records the run. Exits on failure.
+func runSelectedScript(req runRequest) {
+	// Makefile variables: ask in interactive mode, reuse the remembered values otherwise
+	var makeVariables map[string]string
+	variables := runner.ResolveMakeVariables(req.script.Script.Variables)
+	if req.script.Script.Source == "make" && len(variables) > 0 {
+		values, err := req.db.GetMakeVariables(req.project.Key, req.script.Script.Name)
+		if err != nil {
+			fmt.Printf("Warning: failed to load make variables: %v\n", err)
+		}
+
+		if req.interactive {
+			values, err = runner.PromptForMakeVariables(req.script.Script.Name, variables, values)
+			if errors.Is(err, runner.ErrPromptCancelled) {
+				os.Exit(0)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := req.db.SaveMakeVariables(req.project.Key, req.script.Script.Name, values); err != nil {
+				fmt.Printf("Warning: failed to save make variables: %v\n", err)
+			}
+		}
+
+		makeVariables = runner.MakeOverrides(variables, values)
+	}
+
 	// Record usage
-	if err := db.RecordUsage(absPath, selectedScript.Script.Name, selectedScript.Script.Source); err != nil {
+	if err := req.db.RecordUsage(req.project.Key, req.script.Script.Name, req.script.Script.Source); err != nil {
 		fmt.Printf("Warning: failed to record usage: %v\n", err)
 	}
+	if zoxide, ok := req.strategy.(runner.ZoxideStrategy); ok {
+		if _, err := req.db.AgeUsage(req.project.Key, zoxide.MaxTotal); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
 
 	// Execute script based on its source
-	if selectedScript.Script.Source == "make" {
-		if len(scriptArgs) > 0 {
-			fmt.Printf("\n🚀 Running: make %s %s\n\n", selectedScript.Script.Name, strings.Join(scriptArgs, " "))
-		} else {
-			fmt.Printf("\n🚀 Running: make %s\n\n", selectedScript.Script.Name)
-		}
-		if err := executeScript("make", selectedScript.Script.Name, false, scriptArgs); err != nil {
-			fmt.Printf("Error: script execution failed: %v\n", err)
-			os.Exit(1)
+	params := runner.BuildScriptArgsParams{
+		Command:        req.script.Script.Source,
+		ScriptName:     runner.ScriptRunName(req.script.Script),
+		UseRun:         req.script.Script.Source != "make", // npm/pnpm/yarn use "run"
+		AdditionalArgs: req.args,
+		Variables:      makeVariables,
+		Dir:            filepath.Join(req.project.Directory, filepath.FromSlash(runner.ScriptWorkspace(req.script.Script))),
+	}
+	location := ""
+	if req.script.Project != nil || params.Dir != req.project.Directory {
+		location = " in " + runner.ShortenHome(params.Dir)
+	}
+	fmt.Printf("\n🚀 Running: %s %s%s\n\n", params.Command, strings.Join(runner.BuildScriptArgs(params), " "), location)
+
+	startedAt := time.Now()
+	runErr := executeScript(params)
+
+	// Record the run for the preview pane history
+	if err := req.db.RecordRun(runner.ScriptRun{
+		Directory:  req.project.Key,
+		ScriptName: req.script.Script.Name,
+		Source:     req.script.Script.Source,
+		Command:    req.script.Script.Command,
+		Args:       runner.JoinArgs(req.args),
+		StartedAt:  startedAt,
+		Duration:   time.Since(startedAt),
+		ExitCode:   runner.ExitCodeFromError(runErr),
+		Context:    req.workContext,
+	}); err != nil {
+		fmt.Printf("Warning: failed to record run: %v\n", err)
+	}
+
+	// Periodic cleanup, if enabled in the config
+	if req.db.GCDue(req.cfg.AutoGCDays) {
+		if _, err := req.db.GC(gcOptions(req.cfg, false)); err != nil {
+			fmt.Printf("Warning: automatic cleanup failed: %v\n", err)
 		}
-	} else {
-		// For npm/pnpm/yarn
-		if len(scriptArgs) > 0 {
-			// npm requires -- separator, pnpm/yarn don't
-			separator := ""
-			if selectedScript.Script.Source == "npm" {
-				separator = "-- "
-			}
-			fmt.Printf("\n🚀 Running: %s run %s %s%s\n\n", selectedScript.Script.Source, selectedScript.Script.Name, separator, strings.Join(scriptArgs, " "))
-		} else {
-			fmt.Printf("\n🚀 Running: %s run %s\n\n", selectedScript.Script.Source, selectedScript.Script.Name)
+	}
+
+	if runErr != nil {
+		fmt.Printf("Error: script execution failed: %v\n", runErr)
+		os.Exit(1)
+	}
+}
+
+// bestMatch returns the best search result for -l, offering near misses if nothing
+// matches exactly. Exits if there is nothing to run.
+func bestMatch(scoredScripts []runner.ScoredScript, searchTerm string) *runner.ScoredScript {
+	searchResults := runner.SearchScripts(scoredScripts, searchTerm)
+	if len(searchResults) == 0 {
+		fmt.Printf("No scripts matching '%s' found\n", searchTerm)
+		os.Exit(1)
+	}
+
+	if searchResults[0].Typo {
+		// Only near misses: list them and offer to run the closest
+		best, err := runner.DidYouMean(searchTerm, searchResults)
+		if err != nil && !errors.Is(err, runner.ErrPromptCancelled) {
+			fmt.Printf("Error: %v\n", err)
 		}
-		if err := executeScript(selectedScript.Script.Source, selectedScript.Script.Name, true, scriptArgs); err != nil {
-			fmt.Printf("Error: script execution failed: %v\n", err)
+		if best == nil {
 			os.Exit(1)
 		}
+		return best
 	}
+
+	// Run first match immediately
+	selected := &searchResults[0]
+	fmt.Printf("Selected: %s → %s\n", selected.Script.Name, selected.Script.Command)
+	return selected
 }
 
-func executeScript(command string, scriptName string, useRun bool, additionalArgs []string) error {
-	cmdArgs := runner.BuildScriptArgs(runner.BuildScriptArgsParams{
-		Command:        command,
-		ScriptName:     scriptName,
-		UseRun:         useRun,
-		AdditionalArgs: additionalArgs,
-	})
+// gcOptions builds the garbage collection policy from the config
+func gcOptions(cfg *runner.Config, dryRun bool) runner.GCOptions {
+	return runner.GCOptions{
+		RetentionDays: cfg.RetentionDays,
+		MaxRunHistory: cfg.MaxRunHistory,
+		DryRun:        dryRun,
+		Workspaces:    cfg.Workspaces,
+	}
+}
+
+// printGCReport summarizes what --gc removed
+func printGCReport(report runner.GCReport, dryRun bool) {
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+
+	fmt.Printf("%s history for %d missing directories\n", verb, len(report.MissingDirectories))
+	for _, directory := range report.MissingDirectories {
+		fmt.Printf("   %s\n", directory)
+	}
+	fmt.Printf("%s %d scripts no longer defined\n", verb, len(report.UndefinedScripts))
+	for _, script := range report.UndefinedScripts {
+		fmt.Printf("   %s (%s) in %s\n", script.ScriptName, script.Source, script.Directory)
+	}
+	if len(report.UndefinedPinned) > 0 {
+		fmt.Printf("Kept %d pinned scripts no longer defined (unpin them to remove)\n", len(report.UndefinedPinned))
+		for _, script := range report.UndefinedPinned {
+			fmt.Printf("   %s (%s) in %s\n", script.ScriptName, script.Source, script.Directory)
+		}
+	}
+	fmt.Printf("%s %d scripts unused beyond the retention period\n", verb, report.ExpiredScripts)
+	fmt.Printf("%s %d old run history entries\n", verb, report.TrimmedRuns)
+	fmt.Printf("%s %d stale cache entries\n", verb, report.CacheEntries)
 
-	cmd := exec.Command(command, cmdArgs...)
+	if dryRun {
+		fmt.Println("\nDry run: nothing was changed")
+		return
+	}
+	fmt.Printf("\n✓ Database vacuumed: %.1f KB → %.1f KB\n", float64(report.BytesBefore)/1024, float64(report.BytesAfter)/1024)
+}
+
+func executeScript(params runner.BuildScriptArgsParams) error {
+	cmdArgs := runner.BuildScriptArgs(params)
+
+	cmd := exec.Command(params.Command, cmdArgs...)
+	cmd.Dir = params.Dir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
+	// Ctrl+C reaches the script through the process group; catch it here (rather than
+	// ignore it, which the child would inherit) so the run still gets recorded
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
 	return cmd.Run()
 }
 
@@ -498,7 +1024,7 @@ USAGE:
     alex-runner [FLAGS] [SEARCH_TERM] [-- SCRIPT_ARGS...]
 
 FLAGS:
-    -l, --last                         Run the most frecent script immediately
+    -l, --last                         Run the suggested next script, or the most frecent, immediately
     -s, --search <term>                Search for scripts matching term
     --list                             List all scripts with frecency scores
     --list-names                       List script names only (for completion)
@@ -507,7 +1033,21 @@ FLAGS:
     --unpin <script>                   Unpin a previously pinned script
     --use-package-json                 Only show package.json scripts (ignore Makefile)
     --use-makefile                     Only show Makefile targets (ignore package.json)
+    --global                           Search scripts of every project with history, run in its directory
+    --projects                         Pick a project with history: print its path when captured, else show its scripts
+    --height <lines|N%|full>           Show the selector below the prompt instead of full screen
     --no-cache                         Re-detect package manager (ignore cached detection)
+    --graph [script]                   Show what a script triggers as a dependency tree
+    --graph-format <format>            Output format for --graph (tree|dot|mermaid)
+    --db <path>                        Use a different usage history database
+    --gc                               Remove stale history, apply retention and vacuum the database
+    --migrate-renames                  Carry history over to renamed scripts (same command)
+    --dry-run                          With --gc or --migrate-renames: show what would change
+    --merge-history <old-path>         Move history recorded at another path into this project
+    --export [file]                    Export history, pins, runs and caches (JSON, or NDJSON for .ndjson/.jsonl)
+    --import <file>                    Import history from an --export file
+    --merge-strategy <strategy>        How --import merges counts (sum|max|overwrite, default: sum)
+    --rewrite-path <old=new>           Map directories on --import (repeatable)
     --reset                            Clear usage history for current directory
     --global-reset                     Clear all usage history
     -h, --help                         Show this help message
@@ -530,7 +1070,22 @@ EXAMPLES:
     alex-runner --pin dev                      # Pin 'dev' script to appear first
     alex-runner --unpin dev                    # Unpin 'dev' script
     alex-runner --use-makefile                 # Only show Makefile targets
+    alex-runner --global dev                   # Pick a dev script from any project, without cd
+    alex-runner --global -l api dev            # Run the best "api dev" match of all projects
+    alex-runner --projects                     # Pick a project, then one of its scripts
+    alex-runner --height 40%                   # Pick below the prompt, using 40% of the terminal
+    cd "$(alex-runner --projects)"             # Pick a project and cd into it
+    cd "$(alex-runner --projects -l web)"      # cd into the best "web" match without asking
+    alex-runner --graph release                # Show everything 'release' triggers
+    alex-runner --graph --graph-format mermaid # Whole script graph as Mermaid for docs
     alex-runner --reset                        # Clear history for current project
+    alex-runner --db /tmp/scratch.db           # Try things without touching your history
+    alex-runner --gc --dry-run                 # See what garbage collection would remove
+    alex-runner --migrate-renames              # Keep frecency after renaming test:unit to unit-test
+    alex-runner --merge-history ~/old/app      # Keep history after moving a project without a git remote
+    alex-runner --export history.json          # Back up history to move to another machine
+    alex-runner --import history.json --rewrite-path /Users/alice/code=/home/alice/src
+                                               # Merge it in, mapping macOS paths to Linux ones
 
 BEHAVIOR:
     By default, alex-runner will:
@@ -540,6 +1095,19 @@ BEHAVIOR:
     4. Display script names, commands, and source (make/npm/pnpm/yarn)
     5. Track usage to improve suggestions over time
     6. Press alt-p in the UI to toggle pin status of selected script
+    7. Group npm pre/post hooks under their main script (alt-h to collapse/expand)
+    8. Press alt-v to preview the full command, description, dependencies and run history
+    9. Press tab to edit arguments before running (pre-filled from the last run)
+    10. Offer to carry history over when a script was renamed (same command, new name)
+    11. Ask for Makefile variables (ENV ?= ..., $(TAG)) before running a make target;
+        values are remembered per target and reused by -l
+    12. Suggest the next script when the run history is clear about it (what usually
+        follows the script you just ran, or what you usually run at this time of day);
+        it is listed first in the selector and run by -l
+    13. Press alt-g to group the list under headers by source, workspace or name
+        prefix (db:*, test:*); alt-c collapses the highlighted group
+    14. Every printable key types into the filter; quit with ctrl+c, or esc once the
+        filter is empty (key bindings and vim-style modes are configurable)
 
     Use --use-makefile or --use-package-json to filter to a single source.
 
@@ -549,7 +1117,50 @@ PINNED SCRIPTS:
     Unpin scripts with: --unpin <script-name>
     Toggle pin in UI with: alt-p (or option-p on Mac)
 
-The tool stores usage data per directory in ~/.config/alex-runner/
+SEARCH SYNTAX:
+    Search terms (positional, -s, or typed in the selector) can filter by metadata:
+    source:make      Only scripts from this source (make, npm, pnpm, yarn)
+    is:pinned        Only pinned scripts
+    is:failed        Only scripts whose last run failed
+    ws:web           Only scripts defined in a workspace whose path contains "web"
+    tag:ci           Only scripts with #ci in their description
+    -e2e             Exclude scripts with "e2e" in the name or command
+    -source:make     Negate any filter
+    Example: alex-runner source:make build, or alex-runner test -e2e
+    When nothing matches, names a typo away are offered instead (tset → test).
+
+FILES:
+    Usage history:  $XDG_STATE_HOME/alex-runner/alex-runner.sqlite.db (~/.local/state/...)
+                    override with --db <path> or ALEX_RUNNER_DB=<path>
+    Cache:          $XDG_CACHE_HOME/alex-runner/cache.sqlite.db (~/.cache/...)
+    Config:         $XDG_CONFIG_HOME/alex-runner/config.json (~/.config/...)
+    History from the old ~/.config/alex-runner/alex-runner.sqlite.db is moved on first run.
+
+ENVIRONMENT:
+    ALEX_RUNNER_DB   Usage history database (see --db)
+    NO_COLOR         Disable colors and styling
+    CLICOLOR_FORCE   Keep colors when output isn't a terminal
+
+CONFIGURATION:
+    Optional settings are read from $XDG_CONFIG_HOME/alex-runner/config.json:
+
+    {
+      "hideLifecycleHooks": true,   // Start pre/post hook groups collapsed
+      "showPreview": true,          // Open the preview pane (alt-v) on start
+      "previewPosition": "auto",    // auto, right or bottom
+      "groupBy": "none",            // Group the selector by none, source, workspace or prefix
+      "height": "",                 // Selector below the prompt, e.g. "40%" or "15" (lines)
+      "theme": "auto",              // auto, dark, light, high-contrast, monochrome or one of "themes"
+      "vimMode": false,             // Start in normal mode: j/k move, q quits, i or / type
+      "keys": {"quit": ["ctrl+c"]}, // Rebind selector actions (see README)
+      "retentionDays": 365,         // --gc forgets unpinned scripts unused this long (0 = never)
+      "maxRunHistory": 100,         // --gc keeps this many runs per script (0 = all)
+      "autoGcDays": 0,              // Run --gc after a script every N days (0 = off)
+      "frecencyStrategy": "decay",  // decay, zoxide or classic
+      "halfLifeDays": 14,           // decay: score halves after this many days unused
+      "maxTotalScore": 1000,        // zoxide: age a project's counts above this total
+      "suggestNext": true           // Suggest the next script from run history patterns
+    }
 
 SHELL COMPLETION:
     Enable tab completion for your shell:
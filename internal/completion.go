@@ -28,7 +28,21 @@ _alex_runner_completion() {
         --list-names
         --use-package-json
         --use-makefile
+        --global
+        --projects
+        --height
         --no-cache
+        --graph
+        --graph-format
+        --db
+        --gc
+        --migrate-renames
+        --dry-run
+        --merge-history
+        --export
+        --import
+        --merge-strategy
+        --rewrite-path
         --reset
         --global-reset
         --generate-completion
@@ -49,6 +63,31 @@ _alex_runner_completion() {
             COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
             return 0
             ;;
+        --graph-format)
+            # Complete with graph output formats
+            COMPREPLY=($(compgen -W "tree dot mermaid" -- "$cur"))
+            return 0
+            ;;
+        --height)
+            # Complete with common heights
+            COMPREPLY=($(compgen -W "40% 50% full" -- "$cur"))
+            return 0
+            ;;
+        --db|--export|--import)
+            # Complete with file paths
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return 0
+            ;;
+        --merge-history)
+            # Complete with directories
+            COMPREPLY=($(compgen -d -- "$cur"))
+            return 0
+            ;;
+        --merge-strategy)
+            # Complete with import merge strategies
+            COMPREPLY=($(compgen -W "sum max overwrite" -- "$cur"))
+            return 0
+            ;;
     esac
 
     # If current word starts with -, complete with flags
@@ -90,7 +129,21 @@ _alex_runner() {
         '--list-names[List script names only (for completion)]' \
         '--use-package-json[Only show package.json scripts]' \
         '--use-makefile[Only show Makefile targets]' \
+        '--global[Search scripts of every project with history]' \
+        '--projects[Pick a project: print its path or show its scripts]' \
+        '--height[Show the selector below the prompt]:height (lines or N%):(40% 50% full)' \
         '--no-cache[Re-detect package manager]' \
+        '--graph[Show what a script triggers as a dependency tree]' \
+        '--graph-format[Output format for --graph]:format:(tree dot mermaid)' \
+        '--db[Path to the usage history database]:database file:_files' \
+        '--gc[Remove stale history, apply retention and vacuum]' \
+        '--migrate-renames[Carry history over to renamed scripts]' \
+        '--dry-run[With --gc or --migrate-renames, show what would change]' \
+        '--merge-history[Merge history from an old project path]:old path:_directories' \
+        '--export[Export history, pins, runs and caches]::export file:_files' \
+        '--import[Import history from an --export file]:import file:_files' \
+        '--merge-strategy[How --import merges counts]:strategy:(sum max overwrite)' \
+        '*--rewrite-path[Map directories on --import (old=new)]:rewrite:' \
         '--reset[Clear usage history for current directory]' \
         '--global-reset[Clear all usage history]' \
         '--generate-completion[Generate completion script]:shell:(bash zsh fish)' \
@@ -145,7 +198,21 @@ complete -c alex-runner -l list -d 'List all scripts with frecency scores'
 complete -c alex-runner -l list-names -d 'List script names only (for completion)'
 complete -c alex-runner -l use-package-json -d 'Only show package.json scripts'
 complete -c alex-runner -l use-makefile -d 'Only show Makefile targets'
+complete -c alex-runner -l global -d 'Search scripts of every project with history'
+complete -c alex-runner -l projects -d 'Pick a project: print its path or show its scripts'
+complete -c alex-runner -l height -d 'Show the selector below the prompt' -r -f -a '40% 50% full'
 complete -c alex-runner -l no-cache -d 'Re-detect package manager'
+complete -c alex-runner -l graph -d 'Show what a script triggers as a dependency tree'
+complete -c alex-runner -l graph-format -d 'Output format for --graph' -r -f -a 'tree dot mermaid'
+complete -c alex-runner -l db -d 'Path to the usage history database' -r -F
+complete -c alex-runner -l gc -d 'Remove stale history, apply retention and vacuum'
+complete -c alex-runner -l migrate-renames -d 'Carry history over to renamed scripts'
+complete -c alex-runner -l dry-run -d 'With --gc or --migrate-renames, show what would change'
+complete -c alex-runner -l merge-history -d 'Merge history from an old project path' -r -f -a '(__fish_complete_directories)'
+complete -c alex-runner -l export -d 'Export history, pins, runs and caches'
+complete -c alex-runner -l import -d 'Import history from an --export file' -r -F
+complete -c alex-runner -l merge-strategy -d 'How --import merges counts' -r -f -a 'sum max overwrite'
+complete -c alex-runner -l rewrite-path -d 'Map directories on --import (old=new)' -r -f
 complete -c alex-runner -l reset -d 'Clear usage history for current directory'
 complete -c alex-runner -l global-reset -d 'Clear all usage history'
 complete -c alex-runner -l generate-completion -d 'Generate completion script' -r -f -a 'bash zsh fish'
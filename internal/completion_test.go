@@ -24,7 +24,24 @@ func TestGenerateBashCompletion(t *testing.T) {
 		{"flag --generate-completion", "--generate-completion"},
 		{"flag --use-package-json", "--use-package-json"},
 		{"flag --use-makefile", "--use-makefile"},
+		{"flag --global", "--global"},
+		{"flag --projects", "--projects"},
+		{"flag --height", "--height"},
+		{"height choices", "40% 50% full"},
 		{"flag --no-cache", "--no-cache"},
+		{"flag --graph", "--graph"},
+		{"graph format choices", "tree dot mermaid"},
+		{"flag --db", "--db"},
+		{"db file completion", "compgen -f"},
+		{"flag --gc", "--gc"},
+		{"flag --migrate-renames", "--migrate-renames"},
+		{"flag --dry-run", "--dry-run"},
+		{"flag --merge-history", "--merge-history"},
+		{"directory completion", "compgen -d"},
+		{"flag --export", "--export"},
+		{"flag --import", "--import"},
+		{"flag --rewrite-path", "--rewrite-path"},
+		{"merge strategy choices", "sum max overwrite"},
 		{"flag --reset", "--reset"},
 		{"flag --global-reset", "--global-reset"},
 		{"double dash handling", "# Handle -- separator"},
@@ -217,23 +234,23 @@ func TestCompletionFormatsLength(t *testing.T) {
 // Test that completions don't contain any obvious syntax errors
 func TestCompletionSyntax(t *testing.T) {
 	tests := []struct {
-		name       string
-		completion string
+		name           string
+		completion     string
 		mustNotContain []string
 	}{
 		{
-			name:       "bash no unmatched quotes",
-			completion: GenerateBashCompletion(),
+			name:           "bash no unmatched quotes",
+			completion:     GenerateBashCompletion(),
 			mustNotContain: []string{},
 		},
 		{
-			name:       "zsh no unmatched quotes",
-			completion: GenerateZshCompletion(),
+			name:           "zsh no unmatched quotes",
+			completion:     GenerateZshCompletion(),
 			mustNotContain: []string{},
 		},
 		{
-			name:       "fish no unmatched quotes",
-			completion: GenerateFishCompletion(),
+			name:           "fish no unmatched quotes",
+			completion:     GenerateFishCompletion(),
 			mustNotContain: []string{},
 		},
 	}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user preferences loaded from the config file
+//
+// Example $XDG_CONFIG_HOME/alex-runner/config.json (~/.config/alex-runner/config.json):
+//
+//	{
+//	  "hideLifecycleHooks": true,
+//	  "showPreview": true,
+//	  "previewPosition": "right",
+//	  "groupBy": "prefix",
+//	  "workspaces": true,
+//	  "height": "40%",
+//	  "theme": "solarized",
+//	  "themes": {"solarized": {"base": "dark", "primary": "#2AA198", "selection": "#073642"}},
+//	  "vimMode": true,
+//	  "keys": {"togglePin": ["alt+p", "ctrl+t"], "quit": ["q", "ctrl+c", "ctrl+d"]},
+//	  "retentionDays": 365,
+//	  "maxRunHistory": 100,
+//	  "autoGcDays": 7,
+//	  "frecencyStrategy": "decay",
+//	  "halfLifeDays": 14,
+//	  "suggestNext": true
+//	}
+type Config struct {
+	// Start npm pre/post hook groups collapsed under their main script
+	HideLifecycleHooks bool `json:"hideLifecycleHooks"`
+
+	// Open the preview pane when the selector starts (toggle with alt+v)
+	ShowPreview bool `json:"showPreview"`
+
+	// Where the preview pane is placed: "auto", "right" or "bottom"
+	// "auto" uses right when the terminal is wide enough, bottom otherwise
+	PreviewPosition string `json:"previewPosition"`
+
+	// Section headers in the selector (cycle with alt+g): "none", "source" (make, npm,
+	// ...), "workspace" (folder of the package.json or Makefile) or "prefix" (db:*, test:*)
+	GroupBy string `json:"groupBy"`
+
+	// In a monorepo root, also list the scripts of its workspace packages ("apps/web/dev")
+	Workspaces bool `json:"workspaces"`
+
+	// Draw the selector below the prompt with this height, a number of lines or a share
+	// of the terminal like "40%", instead of full screen ("" or "full")
+	Height string `json:"height"`
+
+	// Colors: "auto" (dark or light, following the terminal background), "dark", "light",
+	// "high-contrast", "monochrome", or the name of one of the themes below
+	Theme string `json:"theme"`
+
+	// Custom themes by name; colors left out come from their base theme (see ThemeColors)
+	Themes map[string]ThemeColors `json:"themes"`
+
+	// Vim-style modes in the selector: start in normal mode, where j/k move and q quits,
+	// and type into the filter after i or / (esc goes back to normal mode)
+	VimMode bool `json:"vimMode"`
+
+	// Key bindings replacing the defaults, by action (see KeyMap); an empty list unbinds
+	Keys map[string][]string `json:"keys"`
+
+	// --gc forgets unpinned scripts not run for this many days (0 keeps them forever)
+	RetentionDays int `json:"retentionDays"`
+
+	// --gc keeps at most this many run history entries per script (0 keeps all)
+	MaxRunHistory int `json:"maxRunHistory"`
+
+	// Run --gc automatically after a script when the last one was this many days ago (0 disables)
+	AutoGCDays int `json:"autoGcDays"`
+
+	// How usage is ranked: "decay" (exponential decay by half-life), "zoxide" (recency
+	// multipliers with total-score aging) or "classic" (use count plus recency bucket)
+	FrecencyStrategy string `json:"frecencyStrategy"`
+
+	// Decay strategy: days after which a script's score has halved without use
+	HalfLifeDays float64 `json:"halfLifeDays"`
+
+	// Zoxide strategy: once a project's use counts add up to this, they are scaled down
+	// and scripts whose count drops below 1 are forgotten
+	MaxTotalScore int `json:"maxTotalScore"`
+
+	// Suggest the script the run history predicts (what usually follows the last one, or
+	// what usually runs at this time of day) at the top of the selector and for -l
+	SuggestNext bool `json:"suggestNext"`
+}
+
+// DefaultConfig returns the configuration used when no config file exists
+func DefaultConfig() *Config {
+	return &Config{
+		HideLifecycleHooks: false,
+		ShowPreview:        false,
+		PreviewPosition:    "auto",
+		GroupBy:            GroupNone,
+		Theme:              ThemeAuto,
+		RetentionDays:      365,
+		MaxRunHistory:      100,
+		AutoGCDays:         0,
+		FrecencyStrategy:   "decay",
+		HalfLifeDays:       defaultHalfLifeDays,
+		MaxTotalScore:      defaultMaxTotalScore,
+		SuggestNext:        true,
+	}
+}
+
+// LoadConfig loads the user config from $XDG_CONFIG_HOME/alex-runner/config.json
+func LoadConfig() (*Config, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return DefaultConfig(), err
+	}
+
+	return LoadConfigFromPath(filepath.Join(configDir, configFileName))
+}
+
+// LoadConfigFromPath loads config from a specific file, falling back to defaults if it doesn't exist
+func LoadConfigFromPath(configPath string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return DefaultConfig(), fmt.Errorf("failed to parse config %s: %w", configPath, err)
+	}
+
+	return cfg, nil
+}
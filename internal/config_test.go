@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFromPathMissingFile(t *testing.T) {
+	cfg, err := LoadConfigFromPath(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("missing config should not be an error, got %v", err)
+	}
+
+	if cfg.HideLifecycleHooks {
+		t.Error("expected default HideLifecycleHooks = false")
+	}
+}
+
+func TestLoadConfigFromPath(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"hideLifecycleHooks": true}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFromPath() error = %v", err)
+	}
+
+	if !cfg.HideLifecycleHooks {
+		t.Error("expected HideLifecycleHooks = true")
+	}
+}
+
+func TestLoadConfigFromPathInvalidJSON(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{not json`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfigFromPath(configPath)
+	if err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if cfg == nil {
+		t.Fatal("expected default config to be returned alongside the error")
+	}
+}
+
+func TestLoadConfigFromPathPreviewDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"showPreview": true}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFromPath() error = %v", err)
+	}
+
+	if !cfg.ShowPreview {
+		t.Error("expected ShowPreview = true")
+	}
+	if cfg.PreviewPosition != "auto" {
+		t.Errorf("expected unset previewPosition to keep default \"auto\", got %q", cfg.PreviewPosition)
+	}
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"math"
+	"path"
+	"strings"
+)
+
+const (
+	maxContextPaths    = 100 // Changed paths recorded per run; enough to characterise the work
+	contextBoostWeight = 4.0 // A script always used in exactly this context scores up to 5x
+	contextPathsWeight = 0.7 // Share of the context score from changed files; the rest is the branch
+)
+
+// WorkContext is what the working tree looks like when a script is run: the branch
+// and the files with uncommitted changes
+type WorkContext struct {
+	Branch       string
+	ChangedPaths []string // Relative to the repository root, slash separated
+}
+
+// IsEmpty reports whether there is nothing to compare against (outside git, or a
+// detached HEAD with a clean tree)
+func (c WorkContext) IsEmpty() bool {
+	return c.Branch == "" && len(c.ChangedPaths) == 0
+}
+
+// DetectWorkContext reads the current branch and changed files of the git checkout
+// containing directory. Outside git it returns an empty context.
+func DetectWorkContext(directory string) WorkContext {
+	var context WorkContext
+
+	// symbolic-ref also works before the first commit, and fails on a detached HEAD
+	if branch, err := gitOutput(directory, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		context.Branch = branch
+	}
+
+	status, err := gitOutput(directory, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return context
+	}
+	context.ChangedPaths = parseGitStatusPaths(status)
+	return context
+}
+
+// parseGitStatusPaths extracts paths from `git status --porcelain` output, using the
+// new name of renamed files and keeping at most maxContextPaths
+func parseGitStatusPaths(status string) []string {
+	var paths []string
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		p := line[3:]
+		if _, renamed, ok := strings.Cut(p, " -> "); ok {
+			p = renamed
+		}
+		paths = append(paths, strings.Trim(p, `"`))
+		if len(paths) == maxContextPaths {
+			break
+		}
+	}
+	return paths
+}
+
+// pathFeatures describes changed files as a set of comparable traits: every directory
+// name and every extension. "src/Button.stories.tsx" yields dir:src, ext:.tsx and
+// ext:.stories.tsx, so Storybook work is recognised as such.
+func pathFeatures(paths []string) map[string]bool {
+	features := make(map[string]bool)
+	for _, p := range paths {
+		dir, file := path.Split(p)
+		for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+			if part != "" {
+				features["dir:"+part] = true
+			}
+		}
+
+		// Every dotted suffix, ignoring a leading dot (".env" has no extension)
+		name := strings.TrimPrefix(file, ".")
+		for i := strings.Index(name, "."); i >= 0; {
+			features["ext:"+name[i:]] = true
+			next := strings.Index(name[i+1:], ".")
+			if next < 0 {
+				break
+			}
+			i += next + 1
+		}
+	}
+	return features
+}
+
+// weightedJaccard compares two feature sets, 1 for identical and 0 for disjoint sets.
+// Features are weighted by weight, so sharing a rare trait counts for more than
+// sharing one that shows up in every run (like src/).
+func weightedJaccard(a, b map[string]bool, weight func(string) float64) float64 {
+	var shared, union float64
+	for feature := range a {
+		w := weight(feature)
+		union += w
+		if b[feature] {
+			shared += w
+		}
+	}
+	for feature := range b {
+		if !a[feature] {
+			union += weight(feature)
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return shared / union
+}
+
+// contextScores rates each script (keyed "name:source") between 0 and 1 by how closely
+// the contexts it was run in resemble the current one, averaged over its runs with a
+// known context. Changed files make up most of the score; the branch the rest.
+func contextScores(current WorkContext, runs []ScriptRun) map[string]float64 {
+	if current.IsEmpty() || len(runs) == 0 {
+		return nil
+	}
+	currentFeatures := pathFeatures(current.ChangedPaths)
+
+	// Inverse document frequency of each file feature across the history
+	runFeatures := make([]map[string]bool, len(runs))
+	frequency := make(map[string]int)
+	withPaths := 0
+	for i, run := range runs {
+		runFeatures[i] = pathFeatures(run.Context.ChangedPaths)
+		for feature := range runFeatures[i] {
+			frequency[feature]++
+		}
+		if len(runFeatures[i]) > 0 {
+			withPaths++
+		}
+	}
+	weight := func(feature string) float64 {
+		return math.Log(1 + float64(withPaths+1)/float64(frequency[feature]+1))
+	}
+
+	totals := make(map[string]float64)
+	counts := make(map[string]int)
+	for i, run := range runs {
+		if run.Context.IsEmpty() {
+			continue // Recorded before contexts were, or outside git
+		}
+
+		score := 0.0
+		if len(currentFeatures) > 0 {
+			score += contextPathsWeight * weightedJaccard(currentFeatures, runFeatures[i], weight)
+		}
+		if current.Branch != "" && run.Context.Branch == current.Branch {
+			score += 1 - contextPathsWeight
+		}
+
+		key := run.ScriptName + ":" + run.Source
+		totals[key] += score
+		counts[key]++
+	}
+
+	scores := make(map[string]float64, len(totals))
+	for key, total := range totals {
+		scores[key] = total / float64(counts[key])
+	}
+	return scores
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseGitStatusPaths(t *testing.T) {
+	status := " M src/app.ts\nR  old.go -> pkg/new.go\n?? \"docs/with space.md\"\nA  db/migrations/002_users.sql"
+
+	expected := []string{"src/app.ts", "pkg/new.go", "docs/with space.md", "db/migrations/002_users.sql"}
+	if got := parseGitStatusPaths(status); !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseGitStatusPaths() = %v, want %v", got, expected)
+	}
+}
+
+func TestDetectWorkContext(t *testing.T) {
+	dir := initGitRepo(t, "git@github.com:acme/web.git")
+	if err := os.MkdirAll(filepath.Join(dir, "migrations"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "migrations", "001_users.sql"), []byte("CREATE TABLE users;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	context := DetectWorkContext(dir)
+	if context.Branch == "" {
+		t.Error("expected the branch of a new repository to be detected")
+	}
+	if !reflect.DeepEqual(context.ChangedPaths, []string{"migrations/001_users.sql"}) {
+		t.Errorf("expected the untracked migration, got %v", context.ChangedPaths)
+	}
+
+	if context := DetectWorkContext(t.TempDir()); !context.IsEmpty() {
+		t.Errorf("expected an empty context outside git, got %+v", context)
+	}
+}
+
+func TestPathFeatures(t *testing.T) {
+	features := pathFeatures([]string{"src/components/Button.stories.tsx", ".env"})
+
+	for _, feature := range []string{"dir:src", "dir:components", "ext:.tsx", "ext:.stories.tsx"} {
+		if !features[feature] {
+			t.Errorf("expected feature %s in %v", feature, features)
+		}
+	}
+	if features["ext:.env"] {
+		t.Error("dotfiles should not count as an extension")
+	}
+}
+
+func TestRankerBoostsScriptsUsedInSimilarContext(t *testing.T) {
+	scripts := []NPMScript{
+		{Name: "dev", Source: "npm"},
+		{Name: "db:migrate", Source: "npm"},
+		{Name: "storybook", Source: "npm"},
+	}
+	usage := []ScriptUsage{
+		{ScriptName: "dev", Source: "npm", UseCount: 8, LastUsed: testNow.Add(-time.Hour)},
+		{ScriptName: "db:migrate", Source: "npm", UseCount: 4, LastUsed: testNow.Add(-time.Hour)},
+		{ScriptName: "storybook", Source: "npm", UseCount: 4, LastUsed: testNow.Add(-time.Hour)},
+	}
+	runs := []ScriptRun{
+		{ScriptName: "dev", Source: "npm", Context: WorkContext{Branch: "main", ChangedPaths: []string{"src/app.ts"}}},
+		{ScriptName: "db:migrate", Source: "npm", Context: WorkContext{Branch: "users", ChangedPaths: []string{"migrations/001_users.sql"}}},
+		{ScriptName: "storybook", Source: "npm", Context: WorkContext{Branch: "button", ChangedPaths: []string{"src/Button.stories.tsx"}}},
+		{ScriptName: "storybook", Source: "npm"}, // Recorded before contexts; ignored
+	}
+
+	tests := []struct {
+		name     string
+		context  WorkContext
+		expected string
+	}{
+		{"no context", WorkContext{}, "dev"},
+		{"migration changes", WorkContext{Branch: "orders", ChangedPaths: []string{"migrations/002_orders.sql"}}, "db:migrate"},
+		{"story changes", WorkContext{Branch: "card", ChangedPaths: []string{"src/Card.stories.tsx"}}, "storybook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranker := Ranker{Clock: fixedClock(testNow), Context: tt.context, Runs: runs}
+			scored := ranker.ScoreScripts(scripts, usage)
+			if scored[0].Script.Name != tt.expected {
+				t.Errorf("expected %s first, got %s", tt.expected, scored[0].Script.Name)
+			}
+		})
+	}
+}
+
+func TestRecordRunStoresContext(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	context := WorkContext{Branch: "main", ChangedPaths: []string{"go.mod", "internal/db.go"}}
+	db.RecordRun(ScriptRun{Directory: "/test", ScriptName: "test", Source: "make", StartedAt: time.Now(), Context: context})
+	db.RecordRun(ScriptRun{Directory: "/test", ScriptName: "build", Source: "make", StartedAt: time.Now()})
+
+	runs, err := db.GetProjectRuns("/test", RunHistoryLimit)
+	if err != nil {
+		t.Fatalf("GetProjectRuns() error = %v", err)
+	}
+	if len(runs) != 2 || !reflect.DeepEqual(runs[1].Context, context) || !runs[0].Context.IsEmpty() {
+		t.Errorf("expected the context to be stored with its run, got %+v", runs)
+	}
+}
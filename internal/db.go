@@ -2,12 +2,15 @@ package runner
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 type ScriptUsage struct {
@@ -20,71 +23,173 @@ type ScriptUsage struct {
 	IsPinned   bool
 }
 
+// ScriptRun is a single execution of a script, kept for history and the preview pane
+type ScriptRun struct {
+	ID         int
+	Directory  string
+	ScriptName string
+	Source     string
+	Command    string // Command as defined when it was run
+	Args       string // Extra arguments passed after --, shell-quoted
+	StartedAt  time.Time
+	Duration   time.Duration
+	ExitCode   int         // -1 if the process couldn't be started or was killed by a signal
+	Context    WorkContext // Branch and changed files when the run started
+}
+
 type Database struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *sql.DB // Same as db when state and cache share a file
+}
+
+// SQLite connection settings so several alex-runner processes (and shell completion
+// calling --list-names on every TAB) can share the database:
+//   - busy_timeout waits for a lock instead of failing with "database is locked" (set first,
+//     since switching to WAL needs a lock itself)
+//   - WAL lets readers proceed while another process writes
+//   - _txlock=immediate takes the write lock when a transaction begins, so read-then-write
+//     transactions can't deadlock on lock upgrades
+const sqliteConnectionParams = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate"
+
+const (
+	sqliteBusyCode       = 5 // SQLITE_BUSY primary result code
+	sqliteOpenTimeout    = 5 * time.Second
+	sqliteOpenRetryDelay = 10 * time.Millisecond
+)
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
 }
 
 func InitDatabase() (*Database, error) {
-	homeDir, err := os.UserHomeDir()
+	return InitDatabaseFrom("")
+}
+
+// InitDatabaseFrom opens the database at override (the --db flag), $ALEX_RUNNER_DB or
+// the XDG state directory. The first time the XDG location is used, history from the
+// old ~/.config location is moved over.
+func InitDatabaseFrom(override string) (*Database, error) {
+	paths, isDefault, err := ResolveDatabasePaths(override)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "alex-runner")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	if isDefault {
+		legacyPath, err := LegacyDatabasePath()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := moveLegacyDatabase(legacyPath, paths.State); err != nil {
+			return nil, err
+		}
 	}
 
-	dbPath := filepath.Join(configDir, "alex-runner.sqlite.db")
-	return InitDatabaseWithPath(dbPath)
+	return OpenDatabase(paths)
 }
 
+// InitDatabaseWithPath opens a single database file holding both history and caches
 func InitDatabaseWithPath(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return OpenDatabase(DatabasePaths{State: dbPath, Cache: dbPath})
+}
+
+// OpenDatabase opens (creating and migrating as needed) the state and cache databases
+func OpenDatabase(paths DatabasePaths) (*Database, error) {
+	db, err := openSQLite(paths.State, schemaMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	if paths.Cache == paths.State {
+		// Normally created by the baseline migration, but dropped if this file was used
+		// as the state database next to a separate cache before
+		if _, err := db.Exec(packageManagerCacheTable); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create package manager cache: %w", err)
+		}
+		return &Database{db: db, cache: db}, nil
+	}
+
+	cache, err := openSQLite(paths.Cache, cacheMigrations)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// The baseline migration (released before the cache moved out) still creates
+	// package_manager_cache in the state database; with a separate cache it is unused
+	if err := dropTableIfExists(db, "package_manager_cache"); err != nil {
+		db.Close()
+		cache.Close()
+		return nil, err
+	}
+
+	return &Database{db: db, cache: cache}, nil
+}
+
+// openSQLite opens a database file with the shared connection settings and migrates it
+func openSQLite(dbPath string, migrations []migration) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath+"?"+sqliteConnectionParams)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := createSchema(db); err != nil {
+	if err := connectSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := migrateDatabase(db, dbPath, migrations); err != nil {
 		db.Close()
 		return nil, err
 	}
 
-	return &Database{db: db}, nil
+	return db, nil
 }
 
-func createSchema(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS script_usage (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		directory TEXT NOT NULL,
-		script_name TEXT NOT NULL,
-		source TEXT DEFAULT '',
-		last_used TIMESTAMP NOT NULL,
-		use_count INTEGER DEFAULT 1,
-		is_pinned INTEGER DEFAULT 0,
-		UNIQUE(directory, script_name, source)
-	);
+// connectSQLite makes the first connection, retrying while another process switches a
+// brand-new file to WAL mode: that reports SQLITE_BUSY without waiting for the busy timeout
+func connectSQLite(db *sql.DB) error {
+	deadline := time.Now().Add(sqliteOpenTimeout)
+	for {
+		err := db.Ping()
+		if err == nil || !isBusy(err) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(sqliteOpenRetryDelay)
+	}
+}
 
-	CREATE INDEX IF NOT EXISTS idx_directory ON script_usage(directory);
-	CREATE INDEX IF NOT EXISTS idx_frecency ON script_usage(directory, last_used DESC, use_count DESC);
+// isBusy reports whether err is SQLITE_BUSY (or one of its extended codes)
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqliteBusyCode
+}
 
-	CREATE TABLE IF NOT EXISTS package_manager_cache (
-		directory TEXT PRIMARY KEY,
-		package_manager TEXT NOT NULL,
-		detected_at TIMESTAMP NOT NULL
-	);
-	`
+// SchemaVersion returns the schema version the database is at
+func (d *Database) SchemaVersion() (int, error) {
+	return currentSchemaVersion(d.db)
+}
 
-	_, err := db.Exec(schema)
+// withTx runs fn in a transaction, committing if it succeeds and rolling back otherwise
+func (d *Database) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Add columns to existing tables (for migration)
-	_, _ = db.Exec(`ALTER TABLE script_usage ADD COLUMN is_pinned INTEGER DEFAULT 0`)
-	_, _ = db.Exec(`ALTER TABLE script_usage ADD COLUMN source TEXT DEFAULT ''`)
+	if err := fn(tx); err != nil {
+		return err
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -98,13 +203,70 @@ func (d *Database) RecordUsage(directory string, scriptName string, source strin
 		use_count = use_count + 1
 	`
 
-	now := time.Now()
-	_, err := d.db.Exec(query, directory, scriptName, source, now, now)
-	if err != nil {
-		return fmt.Errorf("failed to record usage: %w", err)
-	}
+	return d.withTx(func(tx *sql.Tx) error {
+		now := time.Now()
+		_, err := tx.Exec(query, directory, scriptName, source, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to record usage: %w", err)
+		}
+		return nil
+	})
+}
 
-	return nil
+// AgeUsage scales a project's use counts down once they add up to more than maxTotal,
+// like zoxide's aging: the counts shrink to 90% of maxTotal in total, and unpinned
+// scripts whose count drops below 1 are forgotten. Returns the number forgotten.
+func (d *Database) AgeUsage(directory string, maxTotal int) (int, error) {
+	forgotten := 0
+	err := d.withTx(func(tx *sql.Tx) error {
+		var total int
+		err := tx.QueryRow(`SELECT COALESCE(SUM(use_count), 0) FROM script_usage WHERE directory = ?`, directory).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to total usage: %w", err)
+		}
+		if maxTotal <= 0 || total <= maxTotal {
+			return nil
+		}
+		factor := zoxideAgingFactor * float64(maxTotal) / float64(total)
+
+		type row struct {
+			id       int64
+			useCount int
+			isPinned bool
+		}
+		rows, err := tx.Query(`SELECT id, use_count, COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ?`, directory)
+		if err != nil {
+			return fmt.Errorf("failed to read usage: %w", err)
+		}
+		var usages []row
+		for rows.Next() {
+			var r row
+			var isPinned int
+			if err := rows.Scan(&r.id, &r.useCount, &isPinned); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan usage: %w", err)
+			}
+			r.isPinned = isPinned != 0
+			usages = append(usages, r)
+		}
+		rows.Close()
+
+		for _, r := range usages {
+			aged := float64(r.useCount) * factor
+			if aged < 1 && !r.isPinned {
+				if _, err := tx.Exec(`DELETE FROM script_usage WHERE id = ?`, r.id); err != nil {
+					return fmt.Errorf("failed to forget usage: %w", err)
+				}
+				forgotten++
+				continue
+			}
+			if _, err := tx.Exec(`UPDATE script_usage SET use_count = ? WHERE id = ?`, max(1, int(math.Round(aged))), r.id); err != nil {
+				return fmt.Errorf("failed to age usage: %w", err)
+			}
+		}
+		return nil
+	})
+	return forgotten, err
 }
 
 func (d *Database) GetUsageStats(directory string) ([]ScriptUsage, error) {
@@ -137,24 +299,37 @@ func (d *Database) GetUsageStats(directory string) ([]ScriptUsage, error) {
 }
 
 func (d *Database) ResetDirectory(directory string) error {
-	query := `DELETE FROM script_usage WHERE directory = ?`
-	_, err := d.db.Exec(query, directory)
-	if err != nil {
-		return fmt.Errorf("failed to reset directory: %w", err)
+	for _, query := range []string{
+		`DELETE FROM script_usage WHERE directory = ?`,
+		`DELETE FROM script_runs WHERE directory = ?`,
+		`DELETE FROM make_variables WHERE directory = ?`,
+	} {
+		if _, err := d.db.Exec(query, directory); err != nil {
+			return fmt.Errorf("failed to reset directory: %w", err)
+		}
 	}
 	return nil
 }
 
 func (d *Database) ResetAll() error {
-	query := `DELETE FROM script_usage`
-	_, err := d.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to reset all: %w", err)
+	for _, query := range []string{
+		`DELETE FROM script_usage`,
+		`DELETE FROM script_runs`,
+		`DELETE FROM make_variables`,
+		`DELETE FROM projects`,
+		`DELETE FROM project_directories`,
+	} {
+		if _, err := d.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to reset all: %w", err)
+		}
 	}
 	return nil
 }
 
 func (d *Database) Close() error {
+	if d.cache != d.db {
+		d.cache.Close()
+	}
 	return d.db.Close()
 }
 
@@ -162,7 +337,7 @@ func (d *Database) Close() error {
 func (d *Database) GetCachedPackageManager(directory string) (string, error) {
 	query := `SELECT package_manager FROM package_manager_cache WHERE directory = ?`
 	var packageManager string
-	err := d.db.QueryRow(query, directory).Scan(&packageManager)
+	err := d.cache.QueryRow(query, directory).Scan(&packageManager)
 	if err == sql.ErrNoRows {
 		return "", nil // No cache entry found
 	}
@@ -172,6 +347,26 @@ func (d *Database) GetCachedPackageManager(directory string) (string, error) {
 	return packageManager, nil
 }
 
+// PackageManagerFor returns the package manager of a directory, from the cache unless
+// useCache is false, detecting and caching it otherwise. The error is only a warning:
+// the package manager is always returned.
+func (d *Database) PackageManagerFor(directory string, useCache bool) (string, error) {
+	var cacheErr error
+	if useCache {
+		cached, err := d.GetCachedPackageManager(directory)
+		if cached != "" {
+			return cached, nil
+		}
+		cacheErr = err
+	}
+
+	packageManager := DetectPackageManager(directory)
+	if err := d.SetCachedPackageManager(directory, packageManager); err != nil {
+		return packageManager, err
+	}
+	return packageManager, cacheErr
+}
+
 // SetCachedPackageManager stores the detected package manager for a directory
 func (d *Database) SetCachedPackageManager(directory string, packageManager string) error {
 	query := `
@@ -183,7 +378,7 @@ func (d *Database) SetCachedPackageManager(directory string, packageManager stri
 		detected_at = ?
 	`
 	now := time.Now()
-	_, err := d.db.Exec(query, directory, packageManager, now, packageManager, now)
+	_, err := d.cache.Exec(query, directory, packageManager, now, packageManager, now)
 	if err != nil {
 		return fmt.Errorf("failed to cache package manager: %w", err)
 	}
@@ -192,13 +387,17 @@ func (d *Database) SetCachedPackageManager(directory string, packageManager stri
 
 // PinScript pins a script for the given directory
 func (d *Database) PinScript(directory string, scriptName string, source string) error {
+	return pinScript(d.db, directory, scriptName, source)
+}
+
+func pinScript(db execer, directory string, scriptName string, source string) error {
 	query := `
 	INSERT INTO script_usage (directory, script_name, source, last_used, use_count, is_pinned)
 	VALUES (?, ?, ?, ?, 0, 1)
 	ON CONFLICT(directory, script_name, source)
 	DO UPDATE SET is_pinned = 1
 	`
-	_, err := d.db.Exec(query, directory, scriptName, source, time.Now())
+	_, err := db.Exec(query, directory, scriptName, source, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to pin script: %w", err)
 	}
@@ -207,8 +406,12 @@ func (d *Database) PinScript(directory string, scriptName string, source string)
 
 // UnpinScript unpins a script for the given directory
 func (d *Database) UnpinScript(directory string, scriptName string, source string) error {
+	return unpinScript(d.db, directory, scriptName, source)
+}
+
+func unpinScript(db execer, directory string, scriptName string, source string) error {
 	query := `UPDATE script_usage SET is_pinned = 0 WHERE directory = ? AND script_name = ? AND source = ?`
-	_, err := d.db.Exec(query, directory, scriptName, source)
+	_, err := db.Exec(query, directory, scriptName, source)
 	if err != nil {
 		return fmt.Errorf("failed to unpin script: %w", err)
 	}
@@ -216,36 +419,36 @@ func (d *Database) UnpinScript(directory string, scriptName string, source strin
 }
 
 // TogglePin toggles the pin status of a script
+// The read and the write happen in one transaction so concurrent toggles can't both see the same state
 func (d *Database) TogglePin(directory string, scriptName string, source string) (bool, error) {
-	// Check current pin status
-	var isPinned int
-	query := `SELECT COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ? AND script_name = ? AND source = ?`
-	err := d.db.QueryRow(query, directory, scriptName, source).Scan(&isPinned)
-
-	if err == sql.ErrNoRows {
-		// Script doesn't exist in usage table yet, pin it
-		if err := d.PinScript(directory, scriptName, source); err != nil {
-			return false, err
+	var pinned bool
+	err := d.withTx(func(tx *sql.Tx) error {
+		// Check current pin status
+		var isPinned int
+		query := `SELECT COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ? AND script_name = ? AND source = ?`
+		err := tx.QueryRow(query, directory, scriptName, source).Scan(&isPinned)
+
+		if err == sql.ErrNoRows {
+			// Script doesn't exist in usage table yet, pin it
+			pinned = true
+			return pinScript(tx, directory, scriptName, source)
 		}
-		return true, nil
-	}
-
-	if err != nil {
-		return false, fmt.Errorf("failed to check pin status: %w", err)
-	}
 
-	// Toggle the pin status
-	if isPinned != 0 {
-		if err := d.UnpinScript(directory, scriptName, source); err != nil {
-			return false, err
+		if err != nil {
+			return fmt.Errorf("failed to check pin status: %w", err)
 		}
-		return false, nil
-	} else {
-		if err := d.PinScript(directory, scriptName, source); err != nil {
-			return false, err
+
+		// Toggle the pin status
+		pinned = isPinned == 0
+		if pinned {
+			return pinScript(tx, directory, scriptName, source)
 		}
-		return true, nil
+		return unpinScript(tx, directory, scriptName, source)
+	})
+	if err != nil {
+		return false, err
 	}
+	return pinned, nil
 }
 
 // IsPinned checks if a script is pinned
@@ -294,3 +497,159 @@ func (d *Database) FindScriptsByName(directory string, scriptName string) ([]Scr
 
 	return usages, nil
 }
+
+// RecordRun stores a finished script execution
+func (d *Database) RecordRun(run ScriptRun) error {
+	query := `
+	INSERT INTO script_runs (directory, script_name, source, command, args, started_at, duration_ms, exit_code, branch, changed_paths)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := d.db.Exec(query, run.Directory, run.ScriptName, run.Source, run.Command, run.Args,
+		run.StartedAt, run.Duration.Milliseconds(), run.ExitCode, run.Context.Branch, joinContextPaths(run.Context.ChangedPaths))
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	return nil
+}
+
+// GetRecentRuns returns the most recent executions of a script, newest first
+func (d *Database) GetRecentRuns(directory string, scriptName string, source string, limit int) ([]ScriptRun, error) {
+	query := `
+	SELECT ` + scriptRunColumns + `
+	FROM script_runs
+	WHERE directory = ? AND script_name = ? AND source = ?
+	ORDER BY started_at DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, directory, scriptName, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	return scanScriptRuns(rows)
+}
+
+// GetProjectRuns returns the most recent runs of any script in a project, newest first,
+// for ranking by context and suggesting what to run next
+func (d *Database) GetProjectRuns(directory string, limit int) ([]ScriptRun, error) {
+	query := `
+	SELECT ` + scriptRunColumns + `
+	FROM script_runs
+	WHERE directory = ?
+	ORDER BY started_at DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, directory, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	return scanScriptRuns(rows)
+}
+
+// scriptRunColumns are the script_runs columns read by scanScriptRuns
+const scriptRunColumns = `id, directory, script_name, COALESCE(source, ''), COALESCE(command, ''), COALESCE(args, ''), started_at, duration_ms, exit_code, COALESCE(branch, ''), COALESCE(changed_paths, '')`
+
+// scanScriptRuns reads and closes rows selected with scriptRunColumns
+func scanScriptRuns(rows *sql.Rows) ([]ScriptRun, error) {
+	defer rows.Close()
+
+	var runs []ScriptRun
+	for rows.Next() {
+		var run ScriptRun
+		var durationMs int64
+		var changedPaths string
+		err := rows.Scan(&run.ID, &run.Directory, &run.ScriptName, &run.Source, &run.Command, &run.Args, &run.StartedAt, &durationMs, &run.ExitCode,
+			&run.Context.Branch, &changedPaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		run.Duration = time.Duration(durationMs) * time.Millisecond
+		run.Context.ChangedPaths = splitContextPaths(changedPaths)
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// joinContextPaths stores changed paths one per line
+func joinContextPaths(paths []string) string {
+	return strings.Join(paths, "\n")
+}
+
+// splitContextPaths reverses joinContextPaths
+func splitContextPaths(paths string) []string {
+	if paths == "" {
+		return nil
+	}
+	return strings.Split(paths, "\n")
+}
+
+// GetArgsHistory returns the distinct non-empty argument strings used with a script, most recent first
+func (d *Database) GetArgsHistory(directory string, scriptName string, source string, limit int) ([]string, error) {
+	query := `
+	SELECT args
+	FROM script_runs
+	WHERE directory = ? AND script_name = ? AND source = ? AND args != ''
+	GROUP BY args
+	ORDER BY MAX(started_at) DESC
+	LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, directory, scriptName, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query args history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []string
+	for rows.Next() {
+		var args string
+		if err := rows.Scan(&args); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		history = append(history, args)
+	}
+
+	return history, nil
+}
+
+// GetMakeVariables returns the last values entered for a Make target's variables
+func (d *Database) GetMakeVariables(directory string, target string) (map[string]string, error) {
+	query := `SELECT name, value FROM make_variables WHERE directory = ? AND target = ?`
+
+	rows, err := d.db.Query(query, directory, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query make variables: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// SaveMakeVariables remembers the values entered for a Make target's variables
+func (d *Database) SaveMakeVariables(directory string, target string, values map[string]string) error {
+	query := `
+	INSERT INTO make_variables (directory, target, name, value, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(directory, target, name)
+	DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	for name, value := range values {
+		if _, err := d.db.Exec(query, directory, target, name, value, now); err != nil {
+			return fmt.Errorf("failed to save make variable %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -330,3 +334,326 @@ func TestInitDatabaseCreatesFile(t *testing.T) {
 		t.Fatal("database file should exist after initialization")
 	}
 }
+
+func TestRecordRunAndGetRecentRuns(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	directory := "/test/project"
+	start := time.Now().Add(-time.Hour)
+
+	for i, exitCode := range []int{0, 1, 0} {
+		err := db.RecordRun(ScriptRun{
+			Directory:  directory,
+			ScriptName: "test",
+			Source:     "npm",
+			Command:    "vitest",
+			Args:       "--run",
+			StartedAt:  start.Add(time.Duration(i) * time.Minute),
+			Duration:   1500 * time.Millisecond,
+			ExitCode:   exitCode,
+		})
+		if err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	// Same name from a different source must not show up
+	if err := db.RecordRun(ScriptRun{Directory: directory, ScriptName: "test", Source: "make", StartedAt: time.Now()}); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+
+	runs, err := db.GetRecentRuns(directory, "test", "npm", 2)
+	if err != nil {
+		t.Fatalf("GetRecentRuns() error = %v", err)
+	}
+
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	if runs[0].ExitCode != 0 || runs[1].ExitCode != 1 {
+		t.Errorf("expected newest run first (exit 0 then 1), got %d then %d", runs[0].ExitCode, runs[1].ExitCode)
+	}
+	if runs[0].Duration != 1500*time.Millisecond {
+		t.Errorf("expected duration 1.5s, got %v", runs[0].Duration)
+	}
+	if runs[0].Args != "--run" || runs[0].Command != "vitest" {
+		t.Errorf("unexpected run details: %+v", runs[0])
+	}
+
+	if err := db.ResetDirectory(directory); err != nil {
+		t.Fatalf("ResetDirectory() error = %v", err)
+	}
+	runs, _ = db.GetRecentRuns(directory, "test", "npm", 5)
+	if len(runs) != 0 {
+		t.Errorf("expected runs to be cleared by ResetDirectory, got %d", len(runs))
+	}
+}
+
+func TestGetArgsHistory(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	directory := "/test/project"
+	start := time.Now().Add(-time.Hour)
+	for i, args := range []string{"--watch", "", "src/a.test.ts", "--watch"} {
+		err := db.RecordRun(ScriptRun{
+			Directory:  directory,
+			ScriptName: "test",
+			Source:     "npm",
+			Args:       args,
+			StartedAt:  start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	history, err := db.GetArgsHistory(directory, "test", "npm", 10)
+	if err != nil {
+		t.Fatalf("GetArgsHistory() error = %v", err)
+	}
+
+	expected := []string{"--watch", "src/a.test.ts"}
+	if len(history) != len(expected) {
+		t.Fatalf("GetArgsHistory() = %q, want %q", history, expected)
+	}
+	for i := range expected {
+		if history[i] != expected[i] {
+			t.Errorf("GetArgsHistory()[%d] = %q, want %q", i, history[i], expected[i])
+		}
+	}
+}
+
+func TestMakeVariablesRoundTrip(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	directory := "/test/project"
+	if err := db.SaveMakeVariables(directory, "deploy", map[string]string{"ENV": "staging", "TAG": "v1"}); err != nil {
+		t.Fatalf("SaveMakeVariables() error = %v", err)
+	}
+	if err := db.SaveMakeVariables(directory, "deploy", map[string]string{"ENV": "prod"}); err != nil {
+		t.Fatalf("SaveMakeVariables() error = %v", err)
+	}
+
+	values, err := db.GetMakeVariables(directory, "deploy")
+	if err != nil {
+		t.Fatalf("GetMakeVariables() error = %v", err)
+	}
+	if values["ENV"] != "prod" || values["TAG"] != "v1" {
+		t.Errorf("GetMakeVariables() = %v, want ENV=prod TAG=v1", values)
+	}
+
+	other, _ := db.GetMakeVariables(directory, "build")
+	if len(other) != 0 {
+		t.Errorf("expected no variables for another target, got %v", other)
+	}
+}
+
+func TestConcurrentRecordUsage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	const workers = 16
+	const perWorker = 25
+
+	// Every worker opens its own connection, like separate alex-runner invocations,
+	// including racing on creating and migrating the new database
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := InitDatabaseWithPath(dbPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+
+			for j := 0; j < perWorker; j++ {
+				if err := db.RecordUsage("/test/project", "dev", "npm"); err != nil {
+					errs <- err
+				}
+				if _, err := db.GetUsageStats("/test/project"); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access failed: %v", err)
+	}
+
+	assertUseCount(t, dbPath, workers*perWorker)
+}
+
+func TestConcurrentTogglePin(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	const toggles = 40 // Even, so the script must end up unpinned
+
+	var wg sync.WaitGroup
+	errs := make(chan error, toggles)
+	for i := 0; i < toggles; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := InitDatabaseWithPath(dbPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+
+			if _, err := conn.TogglePin("/test/project", "build", "make"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent toggle failed: %v", err)
+	}
+
+	isPinned, err := db.IsPinned("/test/project", "build", "make")
+	if err != nil {
+		t.Fatalf("IsPinned() error = %v", err)
+	}
+	if isPinned {
+		t.Errorf("expected script to be unpinned after %d toggles (a toggle was lost)", toggles)
+	}
+}
+
+func TestConcurrentRecordUsageAcrossProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns subprocesses")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	const processes = 6
+	const perProcess = 30
+
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestDatabaseHelperProcess$")
+		cmd.Env = append(os.Environ(),
+			"ALEX_RUNNER_TEST_HELPER_DB="+dbPath,
+			"ALEX_RUNNER_TEST_HELPER_COUNT="+strconv.Itoa(perProcess),
+		)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start helper process: %v", err)
+		}
+		cmds[i] = cmd
+	}
+
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("helper process %d failed: %v", i, err)
+		}
+	}
+
+	assertUseCount(t, dbPath, processes*perProcess)
+}
+
+// TestDatabaseHelperProcess isn't a real test: it is run as a subprocess by
+// TestConcurrentRecordUsageAcrossProcesses to record usage from another process
+func TestDatabaseHelperProcess(t *testing.T) {
+	dbPath := os.Getenv("ALEX_RUNNER_TEST_HELPER_DB")
+	if dbPath == "" {
+		return
+	}
+	count, _ := strconv.Atoi(os.Getenv("ALEX_RUNNER_TEST_HELPER_COUNT"))
+
+	db, err := InitDatabaseWithPath(dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	for i := 0; i < count; i++ {
+		if err := db.RecordUsage("/test/project", "dev", "npm"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// assertUseCount checks that no increments were lost
+func assertUseCount(t *testing.T, dbPath string, expected int) {
+	t.Helper()
+
+	db, err := InitDatabaseWithPath(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	stats, err := db.GetUsageStats("/test/project")
+	if err != nil {
+		t.Fatalf("GetUsageStats() error = %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 usage row, got %d", len(stats))
+	}
+	if stats[0].UseCount != expected {
+		t.Errorf("expected use count %d, got %d (lost increments)", expected, stats[0].UseCount)
+	}
+}
+
+func TestAgeUsage(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	db.db.Exec(`INSERT INTO script_usage (directory, script_name, source, last_used, use_count, is_pinned) VALUES
+		('/test', 'dev', 'npm', ?, 90, 0),
+		('/test', 'rare', 'npm', ?, 1, 0),
+		('/test', 'pinned', 'npm', ?, 1, 1),
+		('/other', 'dev', 'npm', ?, 500, 0)`, time.Now(), time.Now(), time.Now(), time.Now())
+
+	forgotten, err := db.AgeUsage("/test", 100)
+	if err != nil {
+		t.Fatalf("AgeUsage() error = %v", err)
+	}
+	if forgotten != 0 {
+		t.Errorf("expected nothing forgotten at a total of 92, got %d", forgotten)
+	}
+
+	db.db.Exec(`UPDATE script_usage SET use_count = 120 WHERE directory = '/test' AND script_name = 'dev'`)
+
+	forgotten, err = db.AgeUsage("/test", 100)
+	if err != nil {
+		t.Fatalf("AgeUsage() error = %v", err)
+	}
+	if forgotten != 1 {
+		t.Errorf("expected the rarely used script to be forgotten, got %d", forgotten)
+	}
+
+	stats, _ := db.GetUsageStats("/test")
+	counts := make(map[string]int)
+	for _, usage := range stats {
+		counts[usage.ScriptName] = usage.UseCount
+	}
+	if counts["dev"] != 89 { // 120 * 0.9 * 100 / 122, rounded
+		t.Errorf("expected dev aged to 89, got %d", counts["dev"])
+	}
+	if _, ok := counts["rare"]; ok {
+		t.Error("expected rare to be forgotten")
+	}
+	if counts["pinned"] != 1 {
+		t.Errorf("expected pinned script to be kept with count 1, got %d", counts["pinned"])
+	}
+
+	if other, _ := db.GetUsageStats("/other"); other[0].UseCount != 500 {
+		t.Error("aging one project must not touch another")
+	}
+}
@@ -1,25 +1,129 @@
 package runner
 
 import (
+	"fmt"
+	"math"
 	"sort"
+	"strings"
 	"time"
 )
 
 const (
 	frequencyWeight = 0.4
 	recencyWeight   = 0.6
+
+	defaultHalfLifeDays  = 14.0 // Decay strategy: a use counts half as much after two weeks
+	defaultMaxTotalScore = 1000 // Zoxide strategy: age a project's counts once they add up to this
+	zoxideAgingFactor    = 0.9  // Zoxide strategy: aging scales counts down to 90% of the maximum
 )
 
+// Clock returns the current time; tests substitute a fixed time
+type Clock func() time.Time
+
+// FrecencyStrategy turns a script's usage history into a ranking score
+type FrecencyStrategy interface {
+	Score(usage ScriptUsage, now time.Time) float64
+}
+
+// ClassicStrategy adds weighted use count to a bucketed recency score. Heavy use long
+// ago keeps a script on top forever, which is why it is no longer the default.
+type ClassicStrategy struct{}
+
+// Score implements FrecencyStrategy
+func (ClassicStrategy) Score(usage ScriptUsage, now time.Time) float64 {
+	return float64(usage.UseCount)*frequencyWeight + timeScoreAt(usage.LastUsed, now)*recencyWeight
+}
+
+// DecayStrategy weighs the use count down exponentially with the time since the last
+// use, so a script's score halves every HalfLife without use
+type DecayStrategy struct {
+	HalfLife time.Duration
+}
+
+// Score implements FrecencyStrategy
+func (s DecayStrategy) Score(usage ScriptUsage, now time.Time) float64 {
+	age := now.Sub(usage.LastUsed)
+	if age < 0 || s.HalfLife <= 0 {
+		return float64(usage.UseCount)
+	}
+	return float64(usage.UseCount) * math.Pow(0.5, float64(age)/float64(s.HalfLife))
+}
+
+// ZoxideStrategy ranks like zoxide: the use count is multiplied by how recently the
+// script ran, and counts are aged (see Database.AgeUsage) once a project's total
+// exceeds MaxTotal, so old favourites fade as new habits form
+type ZoxideStrategy struct {
+	MaxTotal int
+}
+
+// Score implements FrecencyStrategy
+func (ZoxideStrategy) Score(usage ScriptUsage, now time.Time) float64 {
+	age := now.Sub(usage.LastUsed)
+	multiplier := 0.25
+	switch {
+	case age < time.Hour:
+		multiplier = 4
+	case age < 24*time.Hour:
+		multiplier = 2
+	case age < 7*24*time.Hour:
+		multiplier = 0.5
+	}
+	return float64(usage.UseCount) * multiplier
+}
+
+// DefaultFrecencyStrategy is used when the config doesn't choose one
+func DefaultFrecencyStrategy() FrecencyStrategy {
+	return DecayStrategy{HalfLife: time.Duration(defaultHalfLifeDays * 24 * float64(time.Hour))}
+}
+
+// FrecencyStrategyFromConfig builds the strategy named by frecencyStrategy in the config
+func FrecencyStrategyFromConfig(cfg *Config) (FrecencyStrategy, error) {
+	if cfg == nil {
+		return DefaultFrecencyStrategy(), nil
+	}
+
+	switch strings.ToLower(cfg.FrecencyStrategy) {
+	case "", "decay":
+		halfLifeDays := cfg.HalfLifeDays
+		if halfLifeDays <= 0 {
+			halfLifeDays = defaultHalfLifeDays
+		}
+		return DecayStrategy{HalfLife: time.Duration(halfLifeDays * 24 * float64(time.Hour))}, nil
+	case "zoxide":
+		maxTotal := cfg.MaxTotalScore
+		if maxTotal <= 0 {
+			maxTotal = defaultMaxTotalScore
+		}
+		return ZoxideStrategy{MaxTotal: maxTotal}, nil
+	case "classic":
+		return ClassicStrategy{}, nil
+	default:
+		return DefaultFrecencyStrategy(), fmt.Errorf("unknown frecency strategy '%s' (use decay, zoxide or classic)", cfg.FrecencyStrategy)
+	}
+}
+
 type ScoredScript struct {
-	Script       NPMScript
+	Script        NPMScript
 	FrecencyScore float64
-	LastUsed     *time.Time
-	UseCount     int
-	IsPinned     bool
+	LastUsed      *time.Time
+	UseCount      int
+	IsPinned      bool
+	LastRunFailed bool     // The most recent recorded run exited non-zero (needs Ranker.Runs)
+	Project       *Project // Project the script belongs to, set in --global mode (nil otherwise)
+
+	// Rune indexes in the lowercased name and command matched by SearchScripts, for highlighting
+	NameMatches    []int
+	CommandMatches []int
+	Typo           bool // Matched only by SearchScripts' typo tolerance, e.g. "tset" for "test"
 }
 
 func CalculateTimeScore(lastUsed time.Time) float64 {
-	duration := time.Since(lastUsed)
+	return timeScoreAt(lastUsed, time.Now())
+}
+
+// timeScoreAt buckets the time between lastUsed and now for the classic strategy
+func timeScoreAt(lastUsed time.Time, now time.Time) float64 {
+	duration := now.Sub(lastUsed)
 
 	switch {
 	case duration < 24*time.Hour:
@@ -34,19 +138,46 @@ func CalculateTimeScore(lastUsed time.Time) float64 {
 }
 
 func CalculateFrecency(useCount int, lastUsed time.Time) float64 {
-	timeScore := CalculateTimeScore(lastUsed)
-	frequencyScore := float64(useCount)
+	return ClassicStrategy{}.Score(ScriptUsage{UseCount: useCount, LastUsed: lastUsed}, time.Now())
+}
+
+// Ranker scores and sorts scripts from their usage history
+type Ranker struct {
+	Strategy FrecencyStrategy // How usage becomes a score (DefaultFrecencyStrategy if nil)
+	Clock    Clock            // Source of the current time (time.Now if nil)
 
-	return (frequencyScore * frequencyWeight) + (timeScore * recencyWeight)
+	// Context boosts scripts that were run in a similar context before: on the same
+	// branch or with changes to similar files. Runs holds that history (see
+	// Database.GetProjectRuns); without it no boost is applied.
+	Context WorkContext
+	Runs    []ScriptRun
 }
 
+// ScoreScripts ranks scripts with the default strategy
 func ScoreScripts(scripts []NPMScript, usageStats []ScriptUsage) []ScoredScript {
+	return Ranker{}.ScoreScripts(scripts, usageStats)
+}
+
+// ScoreScripts ranks scripts: pinned first, then by frecency score, boosted by up to
+// contextBoostWeight times for scripts used in a context like the current one
+func (r Ranker) ScoreScripts(scripts []NPMScript, usageStats []ScriptUsage) []ScoredScript {
+	strategy := r.Strategy
+	if strategy == nil {
+		strategy = DefaultFrecencyStrategy()
+	}
+	now := time.Now()
+	if r.Clock != nil {
+		now = r.Clock()
+	}
+
 	// Create a map using (script_name, source) as composite key for quick lookup
 	usageMap := make(map[string]ScriptUsage)
 	for _, usage := range usageStats {
 		key := usage.ScriptName + ":" + usage.Source
 		usageMap[key] = usage
 	}
+	similarity := contextScores(r.Context, r.Runs)
+	lastRuns := latestRuns(r.Runs)
 
 	scoredScripts := make([]ScoredScript, 0, len(scripts))
 
@@ -57,10 +188,13 @@ func ScoreScripts(scripts []NPMScript, usageStats []ScriptUsage) []ScoredScript
 
 		key := script.Name + ":" + script.Source
 		if usage, exists := usageMap[key]; exists {
-			scored.FrecencyScore = CalculateFrecency(usage.UseCount, usage.LastUsed)
+			scored.FrecencyScore = strategy.Score(usage, now) * (1 + contextBoostWeight*similarity[key])
 			scored.LastUsed = &usage.LastUsed
 			scored.UseCount = usage.UseCount
 			scored.IsPinned = usage.IsPinned
+			if run, ok := lastRuns[key]; ok {
+				scored.LastRunFailed = run.ExitCode != 0
+			}
 		} else {
 			// New script with no history
 			scored.FrecencyScore = 0.0
@@ -87,15 +221,30 @@ func ScoreScripts(scripts []NPMScript, usageStats []ScriptUsage) []ScoredScript
 	return scoredScripts
 }
 
+// latestRuns returns the most recent run of each script, keyed "name:source"
+func latestRuns(runs []ScriptRun) map[string]ScriptRun {
+	latest := make(map[string]ScriptRun)
+	for _, run := range runs {
+		key := run.ScriptName + ":" + run.Source
+		if current, ok := latest[key]; !ok || run.StartedAt.After(current.StartedAt) {
+			latest[key] = run
+		}
+	}
+	return latest
+}
+
 func GetMostFrecent(scoredScripts []ScoredScript) *ScoredScript {
 	if len(scoredScripts) == 0 {
 		return nil
 	}
 
-	// Scripts are already sorted by frecency descending
-	// Return the first one (highest score)
-	if scoredScripts[0].FrecencyScore > 0 {
-		return &scoredScripts[0]
+	// Scripts are already sorted pinned first, then by frecency descending
+	// Return the first pinned or used one: a pin without runs scores 0 with the
+	// decay and zoxide strategies but still counts as history
+	for i := range scoredScripts {
+		if scoredScripts[i].IsPinned || scoredScripts[i].FrecencyScore > 0 {
+			return &scoredScripts[i]
+		}
 	}
 
 	// If no scripts have been used, return nil
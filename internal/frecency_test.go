@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -135,6 +136,29 @@ func TestGetMostFrecent(t *testing.T) {
 	}
 }
 
+func TestGetMostFrecentPinnedNeverRun(t *testing.T) {
+	scripts := []NPMScript{
+		{Name: "dev", Command: "next dev"},
+		{Name: "deploy", Command: "vercel deploy"},
+	}
+
+	// Pinning records a row with use_count 0, which decays to a score of 0
+	usageStats := []ScriptUsage{
+		{ScriptName: "dev", LastUsed: time.Now().Add(-1 * time.Hour), UseCount: 10},
+		{ScriptName: "deploy", LastUsed: time.Now(), UseCount: 0, IsPinned: true},
+	}
+
+	scoredScripts := ScoreScripts(scripts, usageStats)
+	if scoredScripts[0].Script.Name != "deploy" || scoredScripts[0].FrecencyScore != 0 {
+		t.Fatalf("expected the pinned script on top with score 0, got %+v", scoredScripts[0])
+	}
+
+	mostFrecent := GetMostFrecent(scoredScripts)
+	if mostFrecent == nil || mostFrecent.Script.Name != "deploy" {
+		t.Errorf("expected the pinned 'deploy', got %+v", mostFrecent)
+	}
+}
+
 func TestGetMostFrecentWithNoHistory(t *testing.T) {
 	scripts := []NPMScript{
 		{Name: "dev", Command: "next dev"},
@@ -237,3 +261,115 @@ func findScriptByName(scripts []ScoredScript, name string) *ScoredScript {
 	}
 	return nil
 }
+
+// fixedClock returns a clock that always reports the given time
+func fixedClock(now time.Time) Clock {
+	return func() time.Time { return now }
+}
+
+var testNow = time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func TestDecayStrategyHalfLife(t *testing.T) {
+	strategy := DecayStrategy{HalfLife: 14 * 24 * time.Hour}
+
+	tests := []struct {
+		name     string
+		age      time.Duration
+		expected float64
+	}{
+		{"just used", 0, 8},
+		{"one half-life", 14 * 24 * time.Hour, 4},
+		{"two half-lives", 28 * 24 * time.Hour, 2},
+		{"in the future (clock skew)", -time.Hour, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := ScriptUsage{UseCount: 8, LastUsed: testNow.Add(-tt.age)}
+			if score := strategy.Score(usage, testNow); math.Abs(score-tt.expected) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", score, tt.expected)
+			}
+		})
+	}
+}
+
+func TestZoxideStrategyMultipliers(t *testing.T) {
+	tests := []struct {
+		age      time.Duration
+		expected float64
+	}{
+		{30 * time.Minute, 40},
+		{5 * time.Hour, 20},
+		{3 * 24 * time.Hour, 5},
+		{30 * 24 * time.Hour, 2.5},
+	}
+
+	for _, tt := range tests {
+		usage := ScriptUsage{UseCount: 10, LastUsed: testNow.Add(-tt.age)}
+		if score := (ZoxideStrategy{}).Score(usage, testNow); score != tt.expected {
+			t.Errorf("Score() after %v = %v, want %v", tt.age, score, tt.expected)
+		}
+	}
+}
+
+func TestDailyUseBeatsHeavyUseLongAgo(t *testing.T) {
+	scripts := []NPMScript{
+		{Name: "legacy", Command: "old-build"},
+		{Name: "dev", Command: "vite"},
+	}
+	usageStats := []ScriptUsage{
+		{ScriptName: "legacy", UseCount: 200, LastUsed: testNow.Add(-365 * 24 * time.Hour)},
+		{ScriptName: "dev", UseCount: 7, LastUsed: testNow.Add(-2 * time.Hour)},
+	}
+
+	scored := Ranker{Strategy: DefaultFrecencyStrategy(), Clock: fixedClock(testNow)}.ScoreScripts(scripts, usageStats)
+	if scored[0].Script.Name != "dev" {
+		t.Errorf("decay: expected dev first, got %s", scored[0].Script.Name)
+	}
+
+	// The old algorithm is kept for those who prefer it, and behaves as before
+	scored = Ranker{Strategy: ClassicStrategy{}, Clock: fixedClock(testNow)}.ScoreScripts(scripts, usageStats)
+	if scored[0].Script.Name != "legacy" {
+		t.Errorf("classic: expected legacy first, got %s", scored[0].Script.Name)
+	}
+}
+
+func TestRankerUsesClock(t *testing.T) {
+	scripts := []NPMScript{{Name: "dev", Command: "vite"}}
+	usageStats := []ScriptUsage{{ScriptName: "dev", UseCount: 4, LastUsed: testNow}}
+	ranker := Ranker{Strategy: DecayStrategy{HalfLife: 24 * time.Hour}}
+
+	ranker.Clock = fixedClock(testNow)
+	now := ranker.ScoreScripts(scripts, usageStats)[0].FrecencyScore
+	ranker.Clock = fixedClock(testNow.Add(48 * time.Hour))
+	later := ranker.ScoreScripts(scripts, usageStats)[0].FrecencyScore
+
+	if now != 4 || later != 1 {
+		t.Errorf("expected scores 4 then 1 two half-lives later, got %v and %v", now, later)
+	}
+}
+
+func TestFrecencyStrategyFromConfig(t *testing.T) {
+	tests := []struct {
+		cfg      Config
+		expected FrecencyStrategy
+		wantErr  bool
+	}{
+		{Config{}, DecayStrategy{HalfLife: 14 * 24 * time.Hour}, false},
+		{Config{FrecencyStrategy: "decay", HalfLifeDays: 7}, DecayStrategy{HalfLife: 7 * 24 * time.Hour}, false},
+		{Config{FrecencyStrategy: "Zoxide"}, ZoxideStrategy{MaxTotal: 1000}, false},
+		{Config{FrecencyStrategy: "zoxide", MaxTotalScore: 50}, ZoxideStrategy{MaxTotal: 50}, false},
+		{Config{FrecencyStrategy: "classic"}, ClassicStrategy{}, false},
+		{Config{FrecencyStrategy: "magic"}, DecayStrategy{HalfLife: 14 * 24 * time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		strategy, err := FrecencyStrategyFromConfig(&tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FrecencyStrategyFromConfig(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+		}
+		if strategy != tt.expected {
+			t.Errorf("FrecencyStrategyFromConfig(%+v) = %#v, want %#v", tt.cfg, strategy, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package runner
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+const lastGCMetadataKey = "last_gc"
+
+// errDryRun rolls back the garbage collection transaction after reporting
+var errDryRun = errors.New("dry run")
+
+// GCOptions controls what garbage collection removes
+type GCOptions struct {
+	RetentionDays int  // Forget unpinned scripts not run for this many days (0 keeps them forever)
+	MaxRunHistory int  // Run history entries kept per script (0 keeps all)
+	DryRun        bool // Report what would be removed without changing anything
+	Workspaces    bool // Workspace package scripts are listed (config "workspaces"), so they can be checked
+}
+
+// RemovedScript is a usage row removed because its script is no longer defined
+type RemovedScript struct {
+	Directory  string
+	ScriptName string
+	Source     string
+}
+
+// GCReport describes what garbage collection removed (or would remove in a dry run)
+type GCReport struct {
+	MissingDirectories []string        // Projects whose directory no longer exists
+	UndefinedScripts   []RemovedScript // Scripts deleted from package.json or the Makefile
+	UndefinedPinned    []RemovedScript // Pinned scripts no longer defined, kept since pins are explicit
+	ExpiredScripts     int             // Unpinned scripts not run within the retention period
+	TrimmedRuns        int             // Run history entries beyond the limit or retention period
+	CacheEntries       int             // Package manager cache entries for missing directories
+	BytesBefore        int64           // Database size before vacuuming
+	BytesAfter         int64           // Database size after vacuuming
+}
+
+// historyTables are the tables keyed by project, with the column holding the key
+var historyTables = []struct{ table, column string }{
+	{"script_usage", "directory"},
+	{"script_runs", "directory"},
+	{"make_variables", "directory"},
+	{"projects", "key"},
+	{"project_directories", "key"},
+}
+
+// GC removes history for missing directories and deleted scripts, applies the retention
+// policy, and vacuums the databases
+func (d *Database) GC(opts GCOptions) (GCReport, error) {
+	var report GCReport
+	var err error
+
+	if report.BytesBefore, err = databaseSize(d.db); err != nil {
+		return report, err
+	}
+
+	err = d.withTx(func(tx *sql.Tx) error {
+		if err := gcProjects(tx, opts, &report); err != nil {
+			return err
+		}
+		if err := gcExpired(tx, opts, &report); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		_, err := tx.Exec(`INSERT INTO metadata (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+			lastGCMetadataKey, time.Now().UTC().Format(time.RFC3339))
+		return err
+	})
+	if err != nil && err != errDryRun {
+		return GCReport{}, fmt.Errorf("failed to collect garbage: %w", err)
+	}
+
+	if report.CacheEntries, err = d.gcCache(opts.DryRun); err != nil {
+		return report, err
+	}
+
+	report.BytesAfter = report.BytesBefore
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if _, err := d.db.Exec(`VACUUM`); err != nil {
+		return report, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	if d.cache != d.db {
+		if _, err := d.cache.Exec(`VACUUM`); err != nil {
+			return report, fmt.Errorf("failed to vacuum cache: %w", err)
+		}
+	}
+	if report.BytesAfter, err = databaseSize(d.db); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// LastGC returns when garbage collection last ran (zero if never)
+func (d *Database) LastGC() (time.Time, error) {
+	var value string
+	err := d.db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, lastGCMetadataKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last garbage collection: %w", err)
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GCDue reports whether automatic garbage collection should run, given the interval in days
+func (d *Database) GCDue(intervalDays int) bool {
+	if intervalDays <= 0 {
+		return false
+	}
+	lastGC, err := d.LastGC()
+	if err != nil {
+		return false
+	}
+	return time.Since(lastGC) >= time.Duration(intervalDays)*24*time.Hour
+}
+
+// gcProjects removes history of projects whose directory is gone and of scripts that
+// are no longer defined in projects that still exist. A project keyed by git remote
+// is only gone once none of its checkouts exist; removing one clone or worktree just
+// forgets that directory.
+func gcProjects(tx *sql.Tx, opts GCOptions, report *GCReport) error {
+	checkouts, err := projectDirectories(tx)
+	if err != nil {
+		return err
+	}
+
+	for key, directories := range checkouts {
+		if len(directories) == 0 {
+			continue // Project key never seen on this machine; nothing to check against
+		}
+
+		var existing, missing []string
+		for _, directory := range directories {
+			if _, err := os.Stat(directory); os.IsNotExist(err) {
+				missing = append(missing, directory)
+			} else {
+				existing = append(existing, directory)
+			}
+		}
+
+		if len(existing) == 0 {
+			for _, history := range historyTables {
+				if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s = ?`, history.table, history.column), key); err != nil {
+					return fmt.Errorf("failed to remove history for %s: %w", directories[0], err)
+				}
+			}
+			report.MissingDirectories = append(report.MissingDirectories, directories[0])
+			continue
+		}
+
+		// Other checkouts remain: move the project to the most recent one still there
+		for _, directory := range missing {
+			if _, err := tx.Exec(`DELETE FROM project_directories WHERE key = ? AND directory = ?`, key, directory); err != nil {
+				return fmt.Errorf("failed to forget %s: %w", directory, err)
+			}
+			if _, err := tx.Exec(`UPDATE projects SET directory = ? WHERE key = ? AND directory = ?`, existing[0], key, directory); err != nil {
+				return fmt.Errorf("failed to move project away from %s: %w", directory, err)
+			}
+		}
+
+		if err := gcUndefinedScripts(tx, key, existing[0], opts.Workspaces, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectDirectories maps every history key to the directories it was seen in, most
+// recent first. Path keys are their own directory; project keys have a directory per
+// checkout, and none if they were never used on this machine.
+func projectDirectories(tx *sql.Tx) (map[string][]string, error) {
+	rows, err := tx.Query(`
+	SELECT directory FROM script_usage
+	UNION SELECT directory FROM script_runs
+	UNION SELECT directory FROM make_variables
+	UNION SELECT key FROM projects
+	UNION SELECT key FROM project_directories
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	directories := make(map[string][]string)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		directories[key] = nil
+		if filepath.IsAbs(key) {
+			directories[key] = []string{key}
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`
+	SELECT key, directory FROM projects
+	UNION ALL SELECT key, directory FROM (SELECT key, directory FROM project_directories ORDER BY updated_at DESC)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, directory string
+		if err := rows.Scan(&key, &directory); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		if filepath.IsAbs(key) || slices.Contains(directories[key], directory) {
+			continue
+		}
+		directories[key] = append(directories[key], directory)
+	}
+	return directories, rows.Err()
+}
+
+// definedScripts returns the scripts a directory currently defines, keyed by name and
+// split into Makefile targets and package.json scripts. A file that is missing or can't
+// be parsed yields ok=false for that side: a branch without a Makefile or a syntax error
+// says nothing about which scripts were deleted, so it never wipes history. Workspace
+// package scripts are only read when workspaces is set.
+func definedScripts(directory string, workspaces bool) (makeTargets map[string]bool, makeOK bool, pkgScripts map[string]bool, pkgOK bool) {
+	makeTargets = make(map[string]bool)
+	if MakefileExists(directory) {
+		targets, err := ReadMakefile(directory)
+		makeOK = err == nil
+		for _, target := range targets {
+			makeTargets[target.Name] = true
+		}
+	}
+
+	pkgScripts = make(map[string]bool)
+	if PackageJSONExists(directory) {
+		pkg, err := ReadPackageJSON(directory)
+		pkgOK = err == nil || errors.Is(err, ErrNoScripts) // A monorepo root may only have workspaces
+		if pkg != nil {
+			for _, script := range GetScripts(pkg) {
+				pkgScripts[script.Name] = true
+			}
+		}
+		if workspaces {
+			workspaceScripts, workspacesOK := loadWorkspaceScripts(directory)
+			pkgOK = pkgOK && workspacesOK
+			for _, script := range workspaceScripts {
+				pkgScripts[script.Name] = true
+			}
+		}
+	}
+	return makeTargets, makeOK, pkgScripts, pkgOK
+}
+
+// gcUndefinedScripts removes usage, runs and remembered variables of scripts that were
+// deleted from the project. Pinned scripts are only reported.
+func gcUndefinedScripts(tx *sql.Tx, key string, directory string, workspaces bool, report *GCReport) error {
+	makeTargets, makeOK, pkgScripts, pkgOK := definedScripts(directory, workspaces)
+	if !makeOK && !pkgOK {
+		return nil
+	}
+
+	rows, err := tx.Query(`SELECT script_name, COALESCE(source, ''), COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to read scripts of %s: %w", directory, err)
+	}
+	var removed []RemovedScript
+	for rows.Next() {
+		script := RemovedScript{Directory: directory}
+		var pinned bool
+		if err := rows.Scan(&script.ScriptName, &script.Source, &pinned); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan script: %w", err)
+		}
+		// Package manager sources are compared by name only: switching from npm to pnpm
+		// doesn't make the script history wrong
+		undefined := script.Source == "make" && makeOK && !makeTargets[script.ScriptName] ||
+			script.Source != "make" && pkgOK && !pkgScripts[script.ScriptName] && (workspaces || !isWorkspaceScript(directory, script.ScriptName))
+		switch {
+		case undefined && pinned:
+			report.UndefinedPinned = append(report.UndefinedPinned, script)
+		case undefined:
+			removed = append(removed, script)
+		}
+	}
+	rows.Close()
+
+	for _, script := range removed {
+		for _, query := range []string{
+			`DELETE FROM script_usage WHERE directory = ? AND script_name = ? AND source = ?`,
+			`DELETE FROM script_runs WHERE directory = ? AND script_name = ? AND source = ?`,
+		} {
+			if _, err := tx.Exec(query, key, script.ScriptName, script.Source); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", script.ScriptName, err)
+			}
+		}
+		if script.Source == "make" {
+			if _, err := tx.Exec(`DELETE FROM make_variables WHERE directory = ? AND target = ?`, key, script.ScriptName); err != nil {
+				return fmt.Errorf("failed to remove variables of %s: %w", script.ScriptName, err)
+			}
+		}
+	}
+	report.UndefinedScripts = append(report.UndefinedScripts, removed...)
+	return nil
+}
+
+// isWorkspaceScript reports whether name looks like the script of a workspace package
+// ("apps/web/dev" with an apps/web/package.json), whose history is kept while
+// workspaces aren't listed
+func isWorkspaceScript(directory string, name string) bool {
+	workspace := path.Dir(name)
+	return workspace != "." && PackageJSONExists(filepath.Join(directory, filepath.FromSlash(workspace)))
+}
+
+// gcExpired applies the retention policy to usage and run history
+// Timestamps are compared in Go since stored values may carry different UTC offsets
+func gcExpired(tx *sql.Tx, opts GCOptions, report *GCReport) error {
+	var cutoff time.Time
+	if opts.RetentionDays > 0 {
+		cutoff = time.Now().Add(-time.Duration(opts.RetentionDays) * 24 * time.Hour)
+	}
+
+	if !cutoff.IsZero() {
+		rows, err := tx.Query(`SELECT id, last_used FROM script_usage WHERE COALESCE(is_pinned, 0) = 0`)
+		if err != nil {
+			return fmt.Errorf("failed to read usage: %w", err)
+		}
+		var expired []int64
+		for rows.Next() {
+			var id int64
+			var lastUsed time.Time
+			if err := rows.Scan(&id, &lastUsed); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan usage: %w", err)
+			}
+			if lastUsed.Before(cutoff) {
+				expired = append(expired, id)
+			}
+		}
+		rows.Close()
+
+		if err := deleteIDs(tx, "script_usage", expired); err != nil {
+			return err
+		}
+		report.ExpiredScripts = len(expired)
+	}
+
+	type run struct {
+		id        int64
+		startedAt time.Time
+	}
+	rows, err := tx.Query(`SELECT id, directory, script_name, COALESCE(source, ''), started_at FROM script_runs`)
+	if err != nil {
+		return fmt.Errorf("failed to read run history: %w", err)
+	}
+	runsByScript := make(map[string][]run)
+	for rows.Next() {
+		var r run
+		var directory, scriptName, source string
+		if err := rows.Scan(&r.id, &directory, &scriptName, &source, &r.startedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan run history: %w", err)
+		}
+		key := directory + "\x00" + scriptName + "\x00" + source
+		runsByScript[key] = append(runsByScript[key], r)
+	}
+	rows.Close()
+
+	var trimmed []int64
+	for _, runs := range runsByScript {
+		sort.Slice(runs, func(i, j int) bool { return runs[i].startedAt.After(runs[j].startedAt) })
+		for i, r := range runs {
+			if opts.MaxRunHistory > 0 && i >= opts.MaxRunHistory || !cutoff.IsZero() && r.startedAt.Before(cutoff) {
+				trimmed = append(trimmed, r.id)
+			}
+		}
+	}
+	if err := deleteIDs(tx, "script_runs", trimmed); err != nil {
+		return err
+	}
+	report.TrimmedRuns = len(trimmed)
+	return nil
+}
+
+// deleteIDs removes rows by id
+func deleteIDs(tx *sql.Tx, table string, ids []int64) error {
+	for _, id := range ids {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, table), id); err != nil {
+			return fmt.Errorf("failed to remove from %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// gcCache removes package manager detections for directories that no longer exist
+func (d *Database) gcCache(dryRun bool) (int, error) {
+	rows, err := d.cache.Query(`SELECT directory FROM package_manager_cache`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read package manager cache: %w", err)
+	}
+	var missing []string
+	for rows.Next() {
+		var directory string
+		if err := rows.Scan(&directory); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan package manager cache: %w", err)
+		}
+		if _, err := os.Stat(directory); os.IsNotExist(err) {
+			missing = append(missing, directory)
+		}
+	}
+	rows.Close()
+
+	if dryRun {
+		return len(missing), nil
+	}
+	for _, directory := range missing {
+		if _, err := d.cache.Exec(`DELETE FROM package_manager_cache WHERE directory = ?`, directory); err != nil {
+			return 0, fmt.Errorf("failed to clean package manager cache: %w", err)
+		}
+	}
+	return len(missing), nil
+}
+
+// databaseSize returns the size of the database in bytes
+func databaseSize(db *sql.DB) (int64, error) {
+	var pageCount, pageSize int64
+	if err := db.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+		return 0, fmt.Errorf("failed to read database size: %w", err)
+	}
+	if err := db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read database size: %w", err)
+	}
+	return pageCount * pageSize, nil
+}
@@ -0,0 +1,276 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGCRemovesMissingDirectoriesAndDeletedScripts(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "Makefile"), []byte("build:\n\tgo build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"scripts": {"dev": "vite"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(t.TempDir(), "deleted-project")
+
+	db.RecordUsage(project, "build", "make")
+	db.RecordUsage(project, "deploy", "make")                // Target removed from the Makefile
+	db.RecordUsage(project, "dev", "pnpm")                   // Still defined
+	db.RecordUsage(project, "test:unit", "pnpm")             // Script removed from package.json
+	db.RecordUsage(gone, "build", "make")                    // Whole directory removed
+	db.RecordUsage("git:github.com/acme/x", "build", "make") // Never seen here, left alone
+	db.SaveMakeVariables(project, "deploy", map[string]string{"ENV": "prod"})
+	db.SetCachedPackageManager(gone, "npm")
+
+	report, err := db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+
+	if len(report.MissingDirectories) != 1 || report.MissingDirectories[0] != gone {
+		t.Errorf("expected %s to be reported missing, got %v", gone, report.MissingDirectories)
+	}
+	if len(report.UndefinedScripts) != 2 {
+		t.Errorf("expected 2 undefined scripts, got %+v", report.UndefinedScripts)
+	}
+	if report.CacheEntries != 1 {
+		t.Errorf("expected 1 stale cache entry, got %d", report.CacheEntries)
+	}
+
+	stats, _ := db.GetUsageStats(project)
+	if len(stats) != 2 {
+		t.Errorf("expected build and dev to remain, got %+v", stats)
+	}
+	if stats, _ := db.GetUsageStats(gone); len(stats) != 0 {
+		t.Errorf("expected history of the missing directory to be removed, got %d rows", len(stats))
+	}
+	if stats, _ := db.GetUsageStats("git:github.com/acme/x"); len(stats) != 1 {
+		t.Error("expected history of an unknown project key to be kept")
+	}
+	if variables, _ := db.GetMakeVariables(project, "deploy"); len(variables) != 0 {
+		t.Errorf("expected variables of the removed target to be removed, got %v", variables)
+	}
+	if lastGC, _ := db.LastGC(); lastGC.IsZero() {
+		t.Error("expected the last garbage collection time to be recorded")
+	}
+}
+
+func TestGCKeepsGitProjectWhileACheckoutExists(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	clone := t.TempDir()
+	worktree := filepath.Join(t.TempDir(), "web-feature")
+	if err := os.MkdirAll(worktree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, directory := range []string{clone, worktree} {
+		if err := os.WriteFile(filepath.Join(directory, "package.json"), []byte(`{"scripts": {"dev": "vite"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The worktree was used last, then removed
+	key := "git:github.com/acme/web"
+	for _, directory := range []string{clone, worktree} {
+		if _, err := db.RegisterProject(Project{Key: key, Directory: directory, Remote: "github.com/acme/web"}); err != nil {
+			t.Fatalf("RegisterProject() error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond) // Distinct updated_at
+	}
+	db.RecordUsage(key, "dev", "npm")
+	if err := os.RemoveAll(worktree); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.MissingDirectories) != 0 {
+		t.Errorf("the clone still exists, nothing should be reported missing: %v", report.MissingDirectories)
+	}
+	if stats, _ := db.GetUsageStats(key); len(stats) != 1 {
+		t.Errorf("expected history shared with the clone to be kept, got %d rows", len(stats))
+	}
+	if project, ok := db.CachedProject(clone); !ok || project.Key != key {
+		t.Errorf("expected the clone to still resolve to %s, got %+v", key, project)
+	}
+	if keys, _ := db.HistoryKeysForDirectory(worktree); len(keys) != 1 {
+		t.Errorf("expected the removed worktree to be forgotten, got keys %v", keys)
+	}
+
+	// Without any checkout left the project is gone
+	if err := os.RemoveAll(clone); err != nil {
+		t.Fatal(err)
+	}
+	report, err = db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.MissingDirectories) != 1 || report.MissingDirectories[0] != clone {
+		t.Errorf("expected %s to be reported missing, got %v", clone, report.MissingDirectories)
+	}
+	if stats, _ := db.GetUsageStats(key); len(stats) != 0 {
+		t.Errorf("expected history to be removed with the last checkout, got %d rows", len(stats))
+	}
+}
+
+func TestGCKeepsHistoryWhenDefinitionsCantBeParsed(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{not json`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db.RecordUsage(project, "dev", "npm")
+
+	report, err := db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.UndefinedScripts) != 0 {
+		t.Errorf("a broken package.json should not remove history, got %+v", report.UndefinedScripts)
+	}
+}
+
+func TestGCKeepsHistoryWhenMakefileIsMissing(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	// A branch without a Makefile, while package.json still defines its scripts
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"scripts": {"dev": "vite"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db.RecordUsage(project, "build", "make")
+	db.RecordUsage(project, "deploy", "make")
+	db.PinScript(project, "deploy", "make")
+	db.RecordRun(ScriptRun{Directory: project, ScriptName: "build", Source: "make", StartedAt: time.Now()})
+	db.SaveMakeVariables(project, "deploy", map[string]string{"ENV": "prod"})
+	db.RecordUsage(project, "lint", "npm") // Removed from package.json
+	db.PinScript(project, "lint", "npm")
+
+	report, err := db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+
+	if len(report.UndefinedScripts) != 0 {
+		t.Errorf("nothing should be removed, got %+v", report.UndefinedScripts)
+	}
+	if len(report.UndefinedPinned) != 1 || report.UndefinedPinned[0].ScriptName != "lint" {
+		t.Errorf("expected the pinned lint to be reported, got %+v", report.UndefinedPinned)
+	}
+	if stats, _ := db.GetUsageStats(project); len(stats) != 3 {
+		t.Errorf("expected build, deploy and lint to be kept, got %+v", stats)
+	}
+	if runs, _ := db.GetRecentRuns(project, "build", "make", 10); len(runs) != 1 {
+		t.Errorf("expected the run of build to be kept, got %+v", runs)
+	}
+	if variables, _ := db.GetMakeVariables(project, "deploy"); variables["ENV"] != "prod" {
+		t.Errorf("expected the variables of deploy to be kept, got %v", variables)
+	}
+	if isPinned, _ := db.IsPinned(project, "lint", "npm"); !isPinned {
+		t.Error("a pinned script should never be removed as undefined")
+	}
+}
+
+func TestGCWorkspaceScripts(t *testing.T) {
+	root := createMonorepo(t)
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	db.RecordUsage(root, "apps/web/dev", "pnpm")    // Still defined
+	db.RecordUsage(root, "apps/web/deploy", "pnpm") // Removed from apps/web/package.json
+	db.RecordUsage(root, "apps/gone/dev", "pnpm")   // Not a workspace package
+
+	// Workspaces aren't listed: their scripts can't be checked, so they are kept
+	report, err := db.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.UndefinedScripts) != 1 || report.UndefinedScripts[0].ScriptName != "apps/gone/dev" {
+		t.Errorf("expected only apps/gone/dev to be removed, got %+v", report.UndefinedScripts)
+	}
+
+	report, err = db.GC(GCOptions{Workspaces: true})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.UndefinedScripts) != 1 || report.UndefinedScripts[0].ScriptName != "apps/web/deploy" {
+		t.Errorf("expected apps/web/deploy to be removed, got %+v", report.UndefinedScripts)
+	}
+}
+
+func TestGCRetention(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"scripts": {"old": "a", "pinned": "b", "new": "c"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	longAgo := time.Now().Add(-400 * 24 * time.Hour)
+	for _, name := range []string{"old", "pinned", "new"} {
+		db.RecordUsage(project, name, "npm")
+	}
+	db.PinScript(project, "pinned", "npm")
+	db.db.Exec(`UPDATE script_usage SET last_used = ? WHERE script_name IN ('old', 'pinned')`, longAgo)
+
+	for i := 0; i < 5; i++ {
+		db.RecordRun(ScriptRun{Directory: project, ScriptName: "new", Source: "npm", StartedAt: time.Now().Add(-time.Duration(i) * time.Minute)})
+	}
+	db.RecordRun(ScriptRun{Directory: project, ScriptName: "old", Source: "npm", StartedAt: longAgo})
+
+	report, err := db.GC(GCOptions{RetentionDays: 365, MaxRunHistory: 3})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+
+	if report.ExpiredScripts != 1 {
+		t.Errorf("expected only the unpinned old script to expire, got %d", report.ExpiredScripts)
+	}
+	if report.TrimmedRuns != 3 {
+		t.Errorf("expected 2 surplus runs and 1 expired run to be trimmed, got %d", report.TrimmedRuns)
+	}
+
+	runs, _ := db.GetRecentRuns(project, "new", "npm", 10)
+	if len(runs) != 3 || time.Since(runs[0].StartedAt) > time.Minute {
+		t.Errorf("expected the 3 newest runs to be kept, got %+v", runs)
+	}
+	if isPinned, _ := db.IsPinned(project, "pinned", "npm"); !isPinned {
+		t.Error("pinned scripts should survive retention")
+	}
+}
+
+func TestGCDryRunChangesNothing(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	gone := filepath.Join(t.TempDir(), "deleted-project")
+	db.RecordUsage(gone, "build", "make")
+
+	report, err := db.GC(GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(report.MissingDirectories) != 1 {
+		t.Errorf("expected the missing directory to be reported, got %v", report.MissingDirectories)
+	}
+	if stats, _ := db.GetUsageStats(gone); len(stats) != 1 {
+		t.Error("dry run should not remove anything")
+	}
+	if !db.GCDue(7) {
+		t.Error("dry run should not count as a garbage collection")
+	}
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const globalProjectBoost = 1.0 // In --global mode, scripts of the most used project score up to twice as much
+
+// KnownProjects returns the projects with usage history whose directory still exists,
+// most recently used first. History of a git project lives where it was last seen.
+func (d *Database) KnownProjects() ([]Project, error) {
+	rows, err := d.db.Query(`
+	SELECT u.directory, COALESCE(p.directory, u.directory), COALESCE(p.remote, '')
+	FROM script_usage u
+	LEFT JOIN projects p ON p.key = u.directory
+	GROUP BY u.directory
+	ORDER BY MAX(u.last_used) DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []Project
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var project Project
+		if err := rows.Scan(&project.Key, &project.Directory, &project.Remote); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		if !filepath.IsAbs(project.Directory) || seen[project.Directory] {
+			continue // Never seen on this machine, or older history of the same checkout
+		}
+		if info, err := os.Stat(project.Directory); err != nil || !info.IsDir() {
+			continue
+		}
+		seen[project.Directory] = true
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// ProjectSummary is a known project with its scripts, for --global and --projects
+type ProjectSummary struct {
+	Project  Project
+	Scripts  []ScoredScript // The project's scripts, ranked within the project
+	Score    float64        // Aggregate frecency: the sum of the scripts' scores
+	UseCount int            // Runs of all its scripts
+	LastUsed *time.Time     // Most recent run of any of its scripts
+}
+
+// ProjectSummaries loads and ranks the scripts of every known project, most used
+// project first. Projects whose scripts can't be read are skipped.
+func (d *Database) ProjectSummaries(ranker Ranker, sources ScriptSources) ([]ProjectSummary, error) {
+	projects, err := d.KnownProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []ProjectSummary
+	for i := range projects {
+		project := &projects[i]
+
+		projectSources := sources
+		projectSources.PackageManager, _ = d.PackageManagerFor(project.Directory, true)
+		scripts, _, err := LoadScripts(project.Directory, projectSources)
+		if err != nil || len(scripts) == 0 {
+			continue
+		}
+
+		usage, err := d.GetUsageStats(project.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := ProjectSummary{Project: *project, Scripts: ranker.ScoreScripts(scripts, usage)}
+		for j := range summary.Scripts {
+			scored := &summary.Scripts[j]
+			scored.Project = project
+			summary.Score += scored.FrecencyScore
+			summary.UseCount += scored.UseCount
+			if scored.LastUsed != nil && (summary.LastUsed == nil || scored.LastUsed.After(*summary.LastUsed)) {
+				summary.LastUsed = scored.LastUsed
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Score > summaries[j].Score
+	})
+	return summaries, nil
+}
+
+// GlobalScripts ranks the scripts of every known project together: each script's
+// frecency in its own project, boosted by how much the project is used overall. Pins
+// only matter within a project, so they don't move scripts up here.
+func (d *Database) GlobalScripts(ranker Ranker, sources ScriptSources) ([]ScoredScript, error) {
+	summaries, err := d.ProjectSummaries(ranker, sources)
+	if err != nil || len(summaries) == 0 {
+		return nil, err
+	}
+
+	var scripts []ScoredScript
+	maxProjectScore := summaries[0].Score
+	for _, summary := range summaries {
+		for _, scored := range summary.Scripts {
+			if maxProjectScore > 0 {
+				scored.FrecencyScore *= 1 + globalProjectBoost*summary.Score/maxProjectScore
+			}
+			scripts = append(scripts, scored)
+		}
+	}
+	sort.SliceStable(scripts, func(i, j int) bool {
+		return scripts[i].FrecencyScore > scripts[j].FrecencyScore
+	})
+	return scripts, nil
+}
+
+// SearchProjects keeps the projects whose path (with ~ for the home directory)
+// matches every word of the query, best match first, blended with frecency like
+// SearchScripts. A word that is the project's whole folder name scores extra.
+func SearchProjects(summaries []ProjectSummary, query string) []ProjectSummary {
+	tokens := strings.Fields(strings.ToLower(query))
+	if len(tokens) == 0 {
+		return summaries
+	}
+
+	type projectResult struct {
+		summary ProjectSummary
+		score   float64
+	}
+	var results []projectResult
+	for _, summary := range summaries {
+		path := []rune(ShortenHome(summary.Project.Directory))
+		folder := strings.ToLower(filepath.Base(summary.Project.Directory))
+		score, ok := 0.0, true
+		for _, token := range tokens {
+			tokenScore, positions := fuzzyScore(path, []rune(token))
+			if positions == nil {
+				ok = false
+				break
+			}
+			if token == folder {
+				tokenScore += bonusExactName
+			}
+			score += float64(tokenScore)
+		}
+		if ok {
+			score += frecencyBlend * math.Log1p(math.Max(summary.Score, 0))
+			results = append(results, projectResult{summary: summary, score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	matches := make([]ProjectSummary, len(results))
+	for i, result := range results {
+		matches[i] = result.summary
+	}
+	return matches
+}
+
+// ShortenHome abbreviates the home directory at the start of a path to ~
+func ShortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return filepath.Join("~", rest)
+	}
+	return path
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeProject creates a project directory with the given Makefile content
+func writeProject(t *testing.T, makefile string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte(makefile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestKnownProjects(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	pathProject := writeProject(t, "dev:\n\tgo run .\n")
+	gitProject := writeProject(t, "dev:\n\tgo run .\n")
+	gone := filepath.Join(t.TempDir(), "deleted-project")
+
+	db.RecordUsage(pathProject, "dev", "make")
+	db.RecordUsage(gone, "dev", "make")
+	db.RecordUsage("git:github.com/acme/unseen", "dev", "make") // Never checked out here
+	if _, err := db.RegisterProject(Project{Key: "git:github.com/acme/web", Directory: gitProject, Remote: "github.com/acme/web"}); err != nil {
+		t.Fatal(err)
+	}
+	db.RecordUsage("git:github.com/acme/web", "dev", "make")
+
+	projects, err := db.KnownProjects()
+	if err != nil {
+		t.Fatalf("KnownProjects() error = %v", err)
+	}
+
+	found := make(map[string]string)
+	for _, project := range projects {
+		found[project.Key] = project.Directory
+	}
+	if len(found) != 2 || found[pathProject] != pathProject || found["git:github.com/acme/web"] != gitProject {
+		t.Errorf("expected the path project and the git project, got %+v", projects)
+	}
+}
+
+func TestGlobalScripts(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	web := writeProject(t, "dev:\n\tvite\nlint:\n\teslint .\n")
+	api := writeProject(t, "dev:\n\tgo run .\n")
+
+	for i := 0; i < 5; i++ {
+		db.RecordUsage(web, "lint", "make")
+	}
+	db.RecordUsage(web, "dev", "make")
+	db.RecordUsage(api, "dev", "make")
+	db.PinScript(api, "dev", "make")
+
+	scripts, err := db.GlobalScripts(Ranker{}, ScriptSources{Makefile: true, PackageJSON: true})
+	if err != nil {
+		t.Fatalf("GlobalScripts() error = %v", err)
+	}
+	if len(scripts) != 3 {
+		t.Fatalf("expected 3 scripts from both projects, got %d", len(scripts))
+	}
+
+	// The busier project's dev outranks the other dev, pinned or not
+	if scripts[0].Script.Name != "lint" || scripts[1].Project.Directory != web || scripts[2].Project.Directory != api {
+		for _, scored := range scripts {
+			t.Logf("%s in %s: %.2f", scored.Script.Name, scored.Project.Directory, scored.FrecencyScore)
+		}
+		t.Error("expected lint, then web's dev, then api's dev")
+	}
+	if scriptKey(scripts[1]) == scriptKey(scripts[2]) {
+		t.Error("expected dev of each project to have its own key")
+	}
+}
+
+func TestSearchScriptsMatchesProjectInGlobalMode(t *testing.T) {
+	web := &Project{Key: "git:github.com/acme/web", Directory: "/code/web"}
+	api := &Project{Key: "git:github.com/acme/api", Directory: "/code/api"}
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "dev", Command: "vite", Source: "pnpm"}, Project: web, FrecencyScore: 5},
+		{Script: NPMScript{Name: "dev", Command: "go run .", Source: "make"}, Project: api, FrecencyScore: 1},
+	}
+
+	results := SearchScripts(scripts, "api dev")
+	if len(results) != 1 || results[0].Project != api {
+		t.Errorf("expected only the api project's dev, got %v", getScriptNames(results))
+	}
+}
+
+func TestProjectSummaries(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	web := writeProject(t, "dev:\n\tvite\nlint:\n\teslint .\n")
+	api := writeProject(t, "dev:\n\tgo run .\n")
+	for i := 0; i < 3; i++ {
+		db.RecordUsage(web, "lint", "make")
+	}
+	db.RecordUsage(web, "dev", "make")
+	db.RecordUsage(api, "dev", "make")
+
+	summaries, err := db.ProjectSummaries(Ranker{}, ScriptSources{Makefile: true})
+	if err != nil {
+		t.Fatalf("ProjectSummaries() error = %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].Project.Directory != web {
+		t.Fatalf("expected web first, got %+v", summaries)
+	}
+	if web := summaries[0]; web.UseCount != 4 || web.LastUsed == nil || web.Scripts[0].Script.Name != "lint" {
+		t.Errorf("expected 4 runs with lint on top, got %d runs, top %s", web.UseCount, web.Scripts[0].Script.Name)
+	}
+}
+
+func TestSearchProjects(t *testing.T) {
+	summaries := []ProjectSummary{
+		{Project: Project{Directory: "/code/acme/web"}, Score: 1},
+		{Project: Project{Directory: "/code/acme/api"}, Score: 5},
+		{Project: Project{Directory: "/code/website"}, Score: 2},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"/code/acme/web", "/code/acme/api", "/code/website"}},
+		{"api", []string{"/code/acme/api"}},
+		{"acme web", []string{"/code/acme/web"}},
+		{"web", []string{"/code/acme/web", "/code/website"}}, // A whole folder name beats a prefix
+		{"zzz", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, summary := range SearchProjects(summaries, tt.query) {
+			got = append(got, summary.Project.Directory)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SearchProjects(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+}
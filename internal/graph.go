@@ -0,0 +1,486 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DependencyKind describes why running one script triggers another
+type DependencyKind string
+
+const (
+	DependencyPrerequisite DependencyKind = "prerequisite" // Makefile prerequisite
+	DependencyPreHook      DependencyKind = "pre"          // npm pre<name> lifecycle hook
+	DependencyPostHook     DependencyKind = "post"         // npm post<name> lifecycle hook
+	DependencyCall         DependencyKind = "run"          // nested "npm run x" / "make x" inside a command
+)
+
+// GraphNode is a single script or Makefile target in the dependency graph
+type GraphNode struct {
+	Name    string
+	Source  string // "make", "npm", "pnpm", "yarn"
+	Command string
+	Edges   []GraphEdge
+}
+
+// GraphEdge points from a script to something it triggers
+type GraphEdge struct {
+	To   *GraphNode
+	Kind DependencyKind
+}
+
+// DependencyGraph links Makefile targets and package.json scripts by what they trigger
+type DependencyGraph struct {
+	nodes    map[string]*GraphNode
+	order    []*GraphNode // Insertion order for deterministic output
+	incoming map[*GraphNode]int
+}
+
+// Regex to split a shell command into sequential segments
+var commandSeparatorRegex = regexp.MustCompile(`&&|\|\||[;|&]`)
+
+// Regex for redirections and their targets (2>&1, &> out.log, >> log, < input),
+// removed before splitting so their "&" doesn't read as a separator
+var redirectionRegex = regexp.MustCompile(`(?:\d+|&)?(?:>>?|<)&?\s*[^\s;&|<>]+`)
+
+// Flags taking a value, per tool, so the value isn't mistaken for a script name
+var (
+	npmValueFlags    = []string{"--prefix", "-w", "--workspace", "--loglevel", "--userconfig"}
+	pnpmValueFlags   = []string{"-C", "--dir", "--cwd", "--filter", "-F", "--reporter", "--loglevel"}
+	runAllValueFlags = []string{"--npm-path", "--max-parallel"}
+	makeValueFlags   = []string{"-f", "--file", "--makefile", "-C", "--directory", "-I", "--include-dir", "-o", "--old-file", "--assume-old", "-W", "--what-if", "--new-file", "--assume-new"}
+	numberFlags      = []string{"-j", "--jobs", "-l", "--load-average"} // make's optional count
+)
+
+// scriptCall is a nested invocation of another script found inside a command
+type scriptCall struct {
+	name string // May be a glob for npm-run-all style runners
+	make bool   // True when invoked through make rather than a package manager
+}
+
+// BuildDependencyGraph builds a graph from Makefile prerequisites, npm pre/post
+// hooks and "npm run x" / "make x" calls embedded in commands.
+// scripts should contain package.json scripts with their Source already set.
+func BuildDependencyGraph(targets []MakeTarget, scripts []NPMScript) *DependencyGraph {
+	g := &DependencyGraph{
+		nodes:    make(map[string]*GraphNode),
+		incoming: make(map[*GraphNode]int),
+	}
+
+	makeByName := make(map[string]*GraphNode)
+	for _, target := range targets {
+		makeByName[target.Name] = g.addNode(target.Name, "make", target.Command)
+	}
+
+	// Sort package.json scripts so output doesn't depend on map iteration order
+	sortedScripts := make([]NPMScript, len(scripts))
+	copy(sortedScripts, scripts)
+	sort.Slice(sortedScripts, func(i, j int) bool {
+		return sortedScripts[i].Name < sortedScripts[j].Name
+	})
+
+	pkgByName := make(map[string]*GraphNode)
+	for _, script := range sortedScripts {
+		pkgByName[script.Name] = g.addNode(script.Name, script.Source, script.Command)
+	}
+
+	resolve := func(call scriptCall) []*GraphNode {
+		if call.make {
+			return matchNodes(makeByName, call.name)
+		}
+		return matchNodes(pkgByName, call.name)
+	}
+
+	for _, target := range targets {
+		node := makeByName[target.Name]
+		for _, prerequisite := range target.Prerequisites {
+			// Prerequisites that aren't targets are files, not scripts
+			if dep, ok := makeByName[prerequisite]; ok {
+				g.addEdge(node, dep, DependencyPrerequisite)
+			}
+		}
+		for _, call := range findScriptCalls(target.Command) {
+			for _, dep := range resolve(call) {
+				g.addEdge(node, dep, DependencyCall)
+			}
+		}
+	}
+
+	for _, script := range sortedScripts {
+		node := pkgByName[script.Name]
+		if pre, ok := pkgByName["pre"+script.Name]; ok {
+			g.addEdge(node, pre, DependencyPreHook)
+		}
+		for _, call := range findScriptCalls(script.Command) {
+			for _, dep := range resolve(call) {
+				g.addEdge(node, dep, DependencyCall)
+			}
+		}
+		if post, ok := pkgByName["post"+script.Name]; ok {
+			g.addEdge(node, post, DependencyPostHook)
+		}
+	}
+
+	return g
+}
+
+func (g *DependencyGraph) addNode(name string, source string, command string) *GraphNode {
+	key := name + ":" + source
+	if node, exists := g.nodes[key]; exists {
+		return node
+	}
+	node := &GraphNode{Name: name, Source: source, Command: command}
+	g.nodes[key] = node
+	g.order = append(g.order, node)
+	return node
+}
+
+func (g *DependencyGraph) addEdge(from *GraphNode, to *GraphNode, kind DependencyKind) {
+	// A script calling itself (e.g. "build": "pnpm -r build") isn't a dependency
+	if from == to {
+		return
+	}
+	for _, edge := range from.Edges {
+		if edge.To == to {
+			return
+		}
+	}
+	from.Edges = append(from.Edges, GraphEdge{To: to, Kind: kind})
+	g.incoming[to]++
+}
+
+// Node returns the node for a script name and source, or nil if not present
+func (g *DependencyGraph) Node(name string, source string) *GraphNode {
+	return g.nodes[name+":"+source]
+}
+
+// Find returns all nodes with the given name across sources
+func (g *DependencyGraph) Find(name string) []*GraphNode {
+	var found []*GraphNode
+	for _, node := range g.order {
+		if node.Name == name {
+			found = append(found, node)
+		}
+	}
+	return found
+}
+
+// Roots returns the nodes that nothing else triggers
+func (g *DependencyGraph) Roots() []*GraphNode {
+	var roots []*GraphNode
+	for _, node := range g.order {
+		if g.incoming[node] == 0 {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// ExecutionChain returns everything that runs when node is run, in execution
+// order: pre hooks and prerequisites, the script itself, nested calls, then post hooks
+func (g *DependencyGraph) ExecutionChain(node *GraphNode) []*GraphNode {
+	var chain []*GraphNode
+	visited := make(map[*GraphNode]bool)
+
+	var walk func(n *GraphNode)
+	walk = func(n *GraphNode) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+
+		for _, edge := range n.Edges {
+			if edge.Kind == DependencyPreHook || edge.Kind == DependencyPrerequisite {
+				walk(edge.To)
+			}
+		}
+		chain = append(chain, n)
+		for _, edge := range n.Edges {
+			if edge.Kind == DependencyCall {
+				walk(edge.To)
+			}
+		}
+		for _, edge := range n.Edges {
+			if edge.Kind == DependencyPostHook {
+				walk(edge.To)
+			}
+		}
+	}
+
+	if node != nil {
+		walk(node)
+	}
+	return chain
+}
+
+// matchNodes resolves a name (or npm-run-all style glob) against known nodes
+func matchNodes(nodes map[string]*GraphNode, pattern string) []*GraphNode {
+	if !strings.ContainsAny(pattern, "*?[") {
+		if node, ok := nodes[pattern]; ok {
+			return []*GraphNode{node}
+		}
+		return nil
+	}
+
+	// npm-run-all treats ":" like a path separator, so "build:*" matches "build:js"
+	globPattern := strings.ReplaceAll(pattern, ":", "/")
+	var matched []*GraphNode
+	for name, node := range nodes {
+		if ok, _ := path.Match(globPattern, strings.ReplaceAll(name, ":", "/")); ok {
+			matched = append(matched, node)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Name < matched[j].Name
+	})
+	return matched
+}
+
+// findScriptCalls finds nested script invocations inside a shell command
+func findScriptCalls(command string) []scriptCall {
+	var calls []scriptCall
+
+	command = redirectionRegex.ReplaceAllString(command, " ")
+	for _, segment := range commandSeparatorRegex.Split(command, -1) {
+		tokens := strings.Fields(segment)
+		for i := range tokens {
+			tokens[i] = strings.Trim(tokens[i], `"'`)
+		}
+
+		// Skip leading environment assignments and env wrappers
+		for len(tokens) > 0 && (isEnvAssignment(tokens[0]) || tokens[0] == "cross-env" || tokens[0] == "env") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		// Anything after a "cd" runs in a different directory, so stop resolving
+		if tokens[0] == "cd" {
+			break
+		}
+
+		calls = append(calls, parseScriptCall(tokens)...)
+	}
+
+	return calls
+}
+
+func parseScriptCall(tokens []string) []scriptCall {
+	tool := tokens[0]
+	args := tokens[1:]
+
+	switch tool {
+	case "npm":
+		if hasAnyFlag(args, "--prefix", "-w", "--workspace", "--workspaces", "-ws") {
+			return nil
+		}
+		positional := nonFlagArgs(args, npmValueFlags)
+		if len(positional) == 0 {
+			return nil
+		}
+		switch positional[0] {
+		case "run", "run-script", "rum", "urn":
+			if len(positional) > 1 {
+				return []scriptCall{{name: positional[1]}}
+			}
+		case "test", "t", "tst":
+			return []scriptCall{{name: "test"}}
+		case "start", "stop", "restart":
+			return []scriptCall{{name: positional[0]}}
+		}
+
+	case "pnpm", "yarn":
+		if hasAnyFlag(args, "-C", "--dir", "--cwd", "-r", "--recursive", "--filter", "-F") {
+			return nil
+		}
+		positional := nonFlagArgs(args, pnpmValueFlags)
+		if len(positional) == 0 || positional[0] == "workspace" || positional[0] == "workspaces" {
+			return nil
+		}
+		// "pnpm run x" or the shorthand "pnpm x" (only resolved if x is a known script)
+		if positional[0] == "run" {
+			if len(positional) > 1 {
+				return []scriptCall{{name: positional[1]}}
+			}
+			return nil
+		}
+		return []scriptCall{{name: positional[0]}}
+
+	case "run-s", "run-p", "npm-run-all":
+		var calls []scriptCall
+		for _, name := range nonFlagArgs(args, runAllValueFlags) {
+			calls = append(calls, scriptCall{name: name})
+		}
+		return calls
+
+	case "make", "gmake", "$(MAKE)", "${MAKE}":
+		if hasAnyFlag(args, "-C", "--directory", "-f", "--file", "--makefile") {
+			return nil
+		}
+		var calls []scriptCall
+		for _, name := range nonFlagArgs(args, makeValueFlags) {
+			if !isEnvAssignment(name) {
+				calls = append(calls, scriptCall{name: name, make: true})
+			}
+		}
+		return calls
+	}
+
+	return nil
+}
+
+func isEnvAssignment(token string) bool {
+	idx := strings.Index(token, "=")
+	return idx > 0 && !strings.HasPrefix(token, "-")
+}
+
+func hasAnyFlag(args []string, flags ...string) bool {
+	for _, arg := range args {
+		for _, flag := range flags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nonFlagArgs returns the positional arguments, skipping flags and the separate
+// values of valueFlags ("-f other.mk", "--filter web")
+func nonFlagArgs(args []string, valueFlags []string) []string {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			// Everything after -- is passed to the script itself
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		if i+1 < len(args) && takesValue(arg, args[i+1], valueFlags) {
+			i++
+		}
+	}
+	return positional
+}
+
+// takesValue reports whether flag consumes next as its value
+func takesValue(flag string, next string, valueFlags []string) bool {
+	if slices.Contains(valueFlags, flag) {
+		return true
+	}
+	// make -j 4: the job count is optional, so only a number belongs to it
+	if slices.Contains(numberFlags, flag) {
+		_, err := strconv.ParseFloat(next, 64)
+		return err == nil
+	}
+	return false
+}
+
+// RenderGraphTree renders the graph reachable from roots as an ASCII tree
+func RenderGraphTree(roots []*GraphNode) string {
+	var s strings.Builder
+	for i, root := range roots {
+		if i > 0 {
+			s.WriteString("\n")
+		}
+		s.WriteString(fmt.Sprintf("%s [%s]\n", root.Name, root.Source))
+		renderTreeChildren(&s, root, "", map[*GraphNode]bool{root: true})
+	}
+	return s.String()
+}
+
+func renderTreeChildren(s *strings.Builder, node *GraphNode, indent string, path map[*GraphNode]bool) {
+	for i, edge := range node.Edges {
+		branch, childIndent := "├── ", "│   "
+		if i == len(node.Edges)-1 {
+			branch, childIndent = "└── ", "    "
+		}
+
+		label := fmt.Sprintf("%s [%s] · %s", edge.To.Name, edge.To.Source, edge.Kind)
+		if path[edge.To] {
+			// Already on the current path: print it but don't recurse forever
+			s.WriteString(indent + branch + label + " ↺ cycle\n")
+			continue
+		}
+		s.WriteString(indent + branch + label + "\n")
+
+		path[edge.To] = true
+		renderTreeChildren(s, edge.To, indent+childIndent, path)
+		delete(path, edge.To)
+	}
+}
+
+// reachable returns every node reachable from roots in breadth-first order
+func reachable(roots []*GraphNode) []*GraphNode {
+	seen := make(map[*GraphNode]bool)
+	var nodes []*GraphNode
+	queue := append([]*GraphNode{}, roots...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		nodes = append(nodes, node)
+		for _, edge := range node.Edges {
+			queue = append(queue, edge.To)
+		}
+	}
+	return nodes
+}
+
+// RenderGraphDOT renders the graph reachable from roots in Graphviz DOT format
+func RenderGraphDOT(roots []*GraphNode) string {
+	nodes := reachable(roots)
+	quote := func(text string) string {
+		return `"` + strings.ReplaceAll(text, `"`, `\"`) + `"`
+	}
+
+	var s strings.Builder
+	s.WriteString("digraph scripts {\n")
+	s.WriteString("  rankdir=LR;\n")
+	s.WriteString("  node [shape=box];\n")
+	for _, node := range nodes {
+		s.WriteString(fmt.Sprintf("  %s [label=%s];\n", quote(node.Source+":"+node.Name), quote(node.Name+"\\n("+node.Source+")")))
+	}
+	for _, node := range nodes {
+		for _, edge := range node.Edges {
+			s.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n",
+				quote(node.Source+":"+node.Name), quote(edge.To.Source+":"+edge.To.Name), quote(string(edge.Kind))))
+		}
+	}
+	s.WriteString("}\n")
+	return s.String()
+}
+
+// RenderGraphMermaid renders the graph reachable from roots as a Mermaid flowchart
+func RenderGraphMermaid(roots []*GraphNode) string {
+	nodes := reachable(roots)
+	ids := make(map[*GraphNode]string, len(nodes))
+	for i, node := range nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+	}
+
+	var s strings.Builder
+	s.WriteString("graph LR\n")
+	for _, node := range nodes {
+		label := strings.ReplaceAll(fmt.Sprintf("%s (%s)", node.Name, node.Source), `"`, "#quot;")
+		s.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", ids[node], label))
+	}
+	for _, node := range nodes {
+		for _, edge := range node.Edges {
+			s.WriteString(fmt.Sprintf("  %s -->|%s| %s\n", ids[node], edge.Kind, ids[edge.To]))
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,396 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGraphMakefile = `.PHONY: release build test
+
+VERSION := 1.0.0
+
+release: build test ## Ship it
+	pnpm run publish
+
+build: | clean
+	go build ./...
+
+test:
+	go test ./...
+
+clean:
+	rm -rf dist
+`
+
+func TestParseMakefilePrerequisites(t *testing.T) {
+	targets, err := ParseMakefile(strings.NewReader(testGraphMakefile))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	prerequisites := make(map[string][]string)
+	for _, target := range targets {
+		prerequisites[target.Name] = target.Prerequisites
+	}
+
+	if _, exists := prerequisites["VERSION"]; exists {
+		t.Error("variable assignment 'VERSION := 1.0.0' should not be parsed as a target")
+	}
+	if !reflect.DeepEqual(prerequisites["release"], []string{"build", "test"}) {
+		t.Errorf("release prerequisites = %v, want [build test]", prerequisites["release"])
+	}
+	if !reflect.DeepEqual(prerequisites["build"], []string{"clean"}) {
+		t.Errorf("build prerequisites = %v, want [clean] (order-only)", prerequisites["build"])
+	}
+}
+
+func TestParseMakefileKeepsTargetsWithoutRecipe(t *testing.T) {
+	content := "all: build test\n\nbuild:\n\tgo build\n\ntest:\n\tgo test\n"
+	targets, err := ParseMakefile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 targets, got %d", len(targets))
+	}
+	if targets[0].Name != "all" || targets[0].Command != "" {
+		t.Errorf("expected aggregate target 'all' without command, got %+v", targets[0])
+	}
+}
+
+func TestParseMakefileDescriptions(t *testing.T) {
+	content := `# Build the binary
+# for the current platform
+.PHONY: build
+build:
+	go build
+
+lint: ## Run linters
+	golangci-lint run
+
+# Unrelated comment
+
+test:
+	go test
+`
+	targets, err := ParseMakefile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	expected := map[string]struct {
+		description string
+		line        int
+	}{
+		"build": {"Build the binary for the current platform", 4},
+		"lint":  {"Run linters", 7},
+		"test":  {"", 12},
+	}
+
+	for _, target := range targets {
+		want := expected[target.Name]
+		if target.Description != want.description {
+			t.Errorf("%s description = %q, want %q", target.Name, target.Description, want.description)
+		}
+		if target.Line != want.line {
+			t.Errorf("%s line = %d, want %d", target.Name, target.Line, want.line)
+		}
+	}
+}
+
+func TestParseMakefileVariables(t *testing.T) {
+	content := `ENV ?= dev
+REGISTRY := ghcr.io/acme
+export TAG ?= latest
+
+deploy: build
+	docker push $(REGISTRY)/app:$(TAG)
+	kubectl apply -f k8s/${ENV} --context $(CLUSTER)
+	echo $$(date) $(MAKE) $(ENV) $(HOME) $(CFLAGS) $(UNDOCUMENTED)
+
+build:
+	go build ./...
+`
+	targets, err := ParseMakefile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets (assignments are not targets), got %d", len(targets))
+	}
+
+	expected := []MakeVariable{
+		{Name: "TAG", Default: "latest", Conditional: true},
+		{Name: "ENV", Default: "dev", Conditional: true},
+		{Name: "CLUSTER"},
+		{Name: "HOME"},
+		{Name: "UNDOCUMENTED"},
+	}
+	if !reflect.DeepEqual(targets[0].Variables, expected) {
+		t.Errorf("deploy variables = %+v, want %+v", targets[0].Variables, expected)
+	}
+	if len(targets[1].Variables) != 0 {
+		t.Errorf("build should have no variables, got %+v", targets[1].Variables)
+	}
+}
+
+func TestResolveMakeVariables(t *testing.T) {
+	t.Setenv("TAG", "v2")
+	t.Setenv("CLUSTER", "prod-eu")
+
+	content := `TAG ?= latest
+
+push:
+	docker push app:$(TAG) --context $(CLUSTER) --region $(REGION)
+`
+	targets, err := ParseMakefile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	// Parsing doesn't depend on the environment
+	parsed := []MakeVariable{{Name: "TAG", Default: "latest", Conditional: true}, {Name: "CLUSTER"}, {Name: "REGION"}}
+	if !reflect.DeepEqual(targets[0].Variables, parsed) {
+		t.Errorf("push variables = %+v, want %+v", targets[0].Variables, parsed)
+	}
+
+	// CLUSTER is already set, and the environment overrides TAG's ?= default
+	expected := []MakeVariable{{Name: "TAG", Default: "v2", Conditional: true}, {Name: "REGION"}}
+	if got := ResolveMakeVariables(targets[0].Variables); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ResolveMakeVariables() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestFindScriptCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []scriptCall
+	}{
+		{"npm run", "npm run lint && npm run test -- --watch", []scriptCall{{name: "lint"}, {name: "test"}}},
+		{"npm test shorthand", "npm test", []scriptCall{{name: "test"}}},
+		{"pnpm shorthand", "pnpm build", []scriptCall{{name: "build"}}},
+		{"yarn run", "yarn run compile", []scriptCall{{name: "compile"}}},
+		{"env prefix", "NODE_ENV=production pnpm run build", []scriptCall{{name: "build"}}},
+		{"run-s globs", "run-s clean build:*", []scriptCall{{name: "clean"}, {name: "build:*"}}},
+		{"make targets", "make build test VERBOSE=1", []scriptCall{{name: "build", make: true}, {name: "test", make: true}}},
+		{"make variable", "$(MAKE) clean", []scriptCall{{name: "clean", make: true}}},
+		{"make other directory", "make -C docs build", nil},
+		{"pnpm recursive", "pnpm -r build", nil},
+		{"after cd", "cd docs && npm run build", nil},
+		{"plain command", "go build ./...", nil},
+		{"stderr redirect", "make build 2>&1 | tee build.log", []scriptCall{{name: "build", make: true}}},
+		{"redirect all output", "npm run build &> build.log && npm run test > test.log 2>&1", []scriptCall{{name: "build"}, {name: "test"}}},
+		{"make job count", "make -j 4 build", []scriptCall{{name: "build", make: true}}},
+		{"npm flag value", "npm --loglevel warn run lint", []scriptCall{{name: "lint"}}},
+		{"run-s flag value", "run-s --npm-path pnpm clean build", []scriptCall{{name: "clean"}, {name: "build"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := findScriptCalls(tt.command)
+			if !reflect.DeepEqual(calls, tt.expected) {
+				t.Errorf("findScriptCalls(%q) = %v, want %v", tt.command, calls, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNonFlagArgsSkipsFlagValues(t *testing.T) {
+	tests := []struct {
+		args       []string
+		valueFlags []string
+		expected   []string
+	}{
+		{[]string{"-f", "other.mk", "build"}, makeValueFlags, []string{"build"}},
+		{[]string{"--filter", "web", "lint"}, pnpmValueFlags, []string{"lint"}},
+		{[]string{"-w", "web", "run", "build"}, npmValueFlags, []string{"run", "build"}},
+		{[]string{"-j", "build"}, makeValueFlags, []string{"build"}}, // -j without a count
+		{[]string{"--silent", "test", "--", "--watch"}, nil, []string{"test"}},
+	}
+
+	for _, tt := range tests {
+		if got := nonFlagArgs(tt.args, tt.valueFlags); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("nonFlagArgs(%q) = %q, want %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func createTestGraph(t *testing.T) *DependencyGraph {
+	targets, err := ParseMakefile(strings.NewReader(testGraphMakefile))
+	if err != nil {
+		t.Fatalf("ParseMakefile() error = %v", err)
+	}
+
+	scripts := []NPMScript{
+		{Name: "publish", Command: "npm run build && changeset publish", Source: "pnpm"},
+		{Name: "prebuild", Command: "rimraf dist", Source: "pnpm"},
+		{Name: "build", Command: "tsc", Source: "pnpm"},
+		{Name: "postbuild", Command: "make test", Source: "pnpm"},
+		{Name: "lint", Command: "eslint .", Source: "pnpm"},
+	}
+
+	return BuildDependencyGraph(targets, scripts)
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	g := createTestGraph(t)
+
+	release := g.Node("release", "make")
+	if release == nil {
+		t.Fatal("expected make:release node")
+	}
+
+	var edges []string
+	for _, edge := range release.Edges {
+		edges = append(edges, edge.To.Source+":"+edge.To.Name+"("+string(edge.Kind)+")")
+	}
+	expected := []string{"make:build(prerequisite)", "make:test(prerequisite)", "pnpm:publish(run)"}
+	if !reflect.DeepEqual(edges, expected) {
+		t.Errorf("release edges = %v, want %v", edges, expected)
+	}
+
+	build := g.Node("build", "pnpm")
+	var kinds []DependencyKind
+	for _, edge := range build.Edges {
+		kinds = append(kinds, edge.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []DependencyKind{DependencyPreHook, DependencyPostHook}) {
+		t.Errorf("pnpm build edges = %v, want [pre post]", kinds)
+	}
+}
+
+func TestDependencyGraphRoots(t *testing.T) {
+	g := createTestGraph(t)
+
+	var roots []string
+	for _, root := range g.Roots() {
+		roots = append(roots, root.Source+":"+root.Name)
+	}
+
+	expected := []string{"make:release", "pnpm:lint"}
+	if !reflect.DeepEqual(roots, expected) {
+		t.Errorf("Roots() = %v, want %v", roots, expected)
+	}
+
+	if found := g.Find("build"); len(found) != 2 {
+		t.Errorf("Find(build) returned %d nodes, want 2 (make and pnpm)", len(found))
+	}
+}
+
+func TestRenderGraphTree(t *testing.T) {
+	g := createTestGraph(t)
+	tree := RenderGraphTree([]*GraphNode{g.Node("publish", "pnpm")})
+
+	expected := `publish [pnpm]
+└── build [pnpm] · run
+    ├── prebuild [pnpm] · pre
+    └── postbuild [pnpm] · post
+        └── test [make] · run
+`
+	if tree != expected {
+		t.Errorf("RenderGraphTree() =\n%s\nwant\n%s", tree, expected)
+	}
+}
+
+func TestRenderGraphTreeCycle(t *testing.T) {
+	scripts := []NPMScript{
+		{Name: "a", Command: "npm run b", Source: "npm"},
+		{Name: "b", Command: "npm run a", Source: "npm"},
+	}
+	g := BuildDependencyGraph(nil, scripts)
+	tree := RenderGraphTree([]*GraphNode{g.Node("a", "npm")})
+
+	if !strings.Contains(tree, "a [npm] · run ↺ cycle") {
+		t.Errorf("expected cycle marker in tree, got:\n%s", tree)
+	}
+}
+
+func TestRenderGraphDOTAndMermaid(t *testing.T) {
+	g := createTestGraph(t)
+	roots := []*GraphNode{g.Node("release", "make")}
+
+	dot := RenderGraphDOT(roots)
+	for _, want := range []string{"digraph scripts {", `"make:release" -> "make:build" [label="prerequisite"];`, `"pnpm:build" -> "pnpm:prebuild" [label="pre"];`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, "lint") {
+		t.Error("DOT output should only include nodes reachable from the roots")
+	}
+
+	mermaid := RenderGraphMermaid(roots)
+	for _, want := range []string{"graph LR", `n0["release (make)"]`, "n0 -->|prerequisite| n1"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestExecutionChain(t *testing.T) {
+	g := createTestGraph(t)
+
+	var names []string
+	for _, node := range g.ExecutionChain(g.Node("publish", "pnpm")) {
+		names = append(names, node.Name)
+	}
+
+	expected := []string{"publish", "prebuild", "build", "postbuild", "test"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("ExecutionChain(publish) = %v, want %v", names, expected)
+	}
+
+	if chain := g.ExecutionChain(nil); len(chain) != 0 {
+		t.Errorf("ExecutionChain(nil) = %v, want empty", chain)
+	}
+}
+
+func TestLifecycleHookOf(t *testing.T) {
+	scripts := map[string]string{
+		"build":     "tsc",
+		"prebuild":  "rimraf dist",
+		"postbuild": "echo done",
+		"prepare":   "husky install",
+		"pretty":    "prettier .",
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"prebuild", "build"},
+		{"postbuild", "build"},
+		{"build", ""},
+		{"prepare", ""}, // No "pare" script
+		{"pretty", ""},  // No "tty" script
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LifecycleHookOf(tt.name, scripts); got != tt.expected {
+				t.Errorf("LifecycleHookOf(%q) = %q, want %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindScriptLine(t *testing.T) {
+	data := []byte(`{
+  "name": "app",
+  "scripts": {
+    "build": "tsc",
+    "test" : "vitest"
+  }
+}`)
+
+	tests := map[string]int{"build": 4, "test": 5, "missing": 0}
+	for name, expected := range tests {
+		if got := findScriptLine(data, name); got != expected {
+			t.Errorf("findScriptLine(%q) = %d, want %d", name, got, expected)
+		}
+	}
+}
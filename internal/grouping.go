@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"path"
+	"strings"
+)
+
+// Selector grouping modes (config "groupBy", cycled with alt+g)
+const (
+	GroupNone      = "none"
+	GroupSource    = "source"    // make, npm, pnpm, yarn
+	GroupWorkspace = "workspace" // Directory of the package.json or Makefile
+	GroupPrefix    = "prefix"    // Name up to the first colon: db:*, test:*
+)
+
+// groupModes is the order alt+g cycles through
+var groupModes = []string{GroupNone, GroupSource, GroupWorkspace, GroupPrefix}
+
+const (
+	rootWorkspaceGroup = "(root)" // Scripts of the project's own package.json or Makefile
+	otherPrefixGroup   = "other"  // Scripts whose prefix no other script shares
+)
+
+// normalizeGroupMode returns mode if it is a known grouping mode, GroupNone otherwise
+func normalizeGroupMode(mode string) string {
+	for _, known := range groupModes {
+		if mode == known {
+			return mode
+		}
+	}
+	return GroupNone
+}
+
+// nextGroupMode returns the grouping mode after mode in the alt+g cycle, skipping the
+// workspace mode when scripts all come from one workspace
+func nextGroupMode(mode string, scripts []ScoredScript) string {
+	mode = normalizeGroupMode(mode)
+	for i, known := range groupModes {
+		if mode != known {
+			continue
+		}
+		for next := 1; next < len(groupModes); next++ {
+			if candidate := groupModes[(i+next)%len(groupModes)]; hasGroupMode(candidate, scripts) {
+				return candidate
+			}
+		}
+	}
+	return GroupNone
+}
+
+// hasGroupMode reports whether mode is worth offering for scripts: a workspace section
+// only helps when there is more than one
+func hasGroupMode(mode string, scripts []ScoredScript) bool {
+	if mode != GroupWorkspace {
+		return true
+	}
+	for _, scored := range scripts {
+		if scriptGroup(scored, mode) != scriptGroup(scripts[0], mode) {
+			return true
+		}
+	}
+	return false
+}
+
+// namePrefix is a script name up to its first colon ("db" for "db:migrate"), or the
+// whole name
+func namePrefix(name string) string {
+	prefix, _, _ := strings.Cut(name, ":")
+	return prefix
+}
+
+// scriptGroup returns the section a script is listed under. Prefix groups are
+// resolved by groupScripts, since a prefix only makes a group when scripts share it.
+func scriptGroup(scored ScoredScript, mode string) string {
+	switch mode {
+	case GroupSource:
+		return scored.Script.Source
+	case GroupWorkspace:
+		workspace := ScriptWorkspace(scored.Script)
+		if scored.Project != nil {
+			return path.Join(ShortenHome(scored.Project.Directory), workspace)
+		}
+		if workspace == "" {
+			return rootWorkspaceGroup
+		}
+		return workspace
+	case GroupPrefix:
+		return namePrefix(scored.Script.Name)
+	}
+	return ""
+}
+
+// groupScripts reorders scripts (with the hook nesting depth of each) into sections:
+// sections in order of their first script, scripts in their original order within a
+// section, and hooks staying with their main script. Returns the section of each entry.
+func groupScripts(scripts []ScoredScript, depths []int, mode string) ([]ScoredScript, []int, []string) {
+	// An entry is a top-level script followed by its nested hooks
+	type entry struct {
+		start, end int
+		group      string
+	}
+	var entries []entry
+	for i := range scripts {
+		if i < len(depths) && depths[i] > 0 && len(entries) > 0 {
+			entries[len(entries)-1].end = i + 1
+			continue
+		}
+		entries = append(entries, entry{start: i, end: i + 1, group: scriptGroup(scripts[i], mode)})
+	}
+
+	if mode == GroupPrefix {
+		// Prefixes shared by a single script go to a common section at the end
+		counts := make(map[string]int)
+		for _, e := range entries {
+			counts[e.group]++
+		}
+		for i := range entries {
+			if counts[entries[i].group] > 1 {
+				entries[i].group += ":*"
+			} else {
+				entries[i].group = otherPrefixGroup
+			}
+		}
+	}
+
+	var order []string
+	members := make(map[string][]entry)
+	for _, e := range entries {
+		if _, seen := members[e.group]; !seen && e.group != otherPrefixGroup {
+			order = append(order, e.group)
+		}
+		members[e.group] = append(members[e.group], e)
+	}
+	if _, ok := members[otherPrefixGroup]; ok {
+		order = append(order, otherPrefixGroup)
+	}
+
+	grouped := make([]ScoredScript, 0, len(scripts))
+	groupedDepths := make([]int, 0, len(scripts))
+	groups := make([]string, 0, len(scripts))
+	for _, group := range order {
+		for _, e := range members[group] {
+			for i := e.start; i < e.end; i++ {
+				depth := 0
+				if i < len(depths) {
+					depth = depths[i]
+				}
+				grouped = append(grouped, scripts[i])
+				groupedDepths = append(groupedDepths, depth)
+				groups = append(groups, group)
+			}
+		}
+	}
+	return grouped, groupedDepths, groups
+}
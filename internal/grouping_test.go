@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupScriptsByPrefix(t *testing.T) {
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "test:unit", Source: "pnpm"}},
+		{Script: NPMScript{Name: "dev", Source: "pnpm"}},
+		{Script: NPMScript{Name: "db:migrate", Source: "pnpm"}},
+		{Script: NPMScript{Name: "predb:migrate", Source: "pnpm", HookOf: "db:migrate"}},
+		{Script: NPMScript{Name: "lint", Source: "pnpm"}},
+		{Script: NPMScript{Name: "test", Source: "make"}},
+		{Script: NPMScript{Name: "db:seed", Source: "pnpm"}},
+	}
+	depths := []int{0, 0, 0, 1, 0, 0, 0}
+
+	grouped, groupedDepths, groups := groupScripts(scripts, depths, GroupPrefix)
+
+	var names []string
+	for _, scored := range grouped {
+		names = append(names, scored.Script.Name)
+	}
+	// Sections in order of their best script, single prefixes last, hooks kept with their script
+	expectedNames := []string{"test:unit", "test", "db:migrate", "predb:migrate", "db:seed", "dev", "lint"}
+	expectedGroups := []string{"test:*", "test:*", "db:*", "db:*", "db:*", "other", "other"}
+	expectedDepths := []int{0, 0, 0, 1, 0, 0, 0}
+
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("names = %v, want %v", names, expectedNames)
+	}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("groups = %v, want %v", groups, expectedGroups)
+	}
+	if !reflect.DeepEqual(groupedDepths, expectedDepths) {
+		t.Errorf("depths = %v, want %v", groupedDepths, expectedDepths)
+	}
+}
+
+func TestScriptGroup(t *testing.T) {
+	tests := []struct {
+		scored   ScoredScript
+		mode     string
+		expected string
+	}{
+		{ScoredScript{Script: NPMScript{Name: "build", Source: "make"}}, GroupSource, "make"},
+		{ScoredScript{Script: NPMScript{Name: "dev", File: "package.json"}}, GroupWorkspace, "(root)"},
+		{ScoredScript{Script: NPMScript{Name: "dev", File: "apps/web/package.json"}}, GroupWorkspace, "apps/web"},
+		{ScoredScript{Script: NPMScript{Name: "dev", File: "apps/web/package.json"}, Project: &Project{Directory: "/srv/shop"}}, GroupWorkspace, "/srv/shop/apps/web"},
+		{ScoredScript{Script: NPMScript{Name: "db:migrate:dev"}}, GroupPrefix, "db"},
+		{ScoredScript{Script: NPMScript{Name: "build"}}, GroupNone, ""},
+	}
+
+	for _, tt := range tests {
+		if got := scriptGroup(tt.scored, tt.mode); got != tt.expected {
+			t.Errorf("scriptGroup(%q, %s) = %q, want %q", tt.scored.Script.Name, tt.mode, got, tt.expected)
+		}
+	}
+}
+
+func TestNextGroupMode(t *testing.T) {
+	monorepo := []ScoredScript{
+		{Script: NPMScript{Name: "dev", File: "package.json"}},
+		{Script: NPMScript{Name: "apps/web/dev", File: "apps/web/package.json"}},
+	}
+	mode := "bogus"
+	var cycle []string
+	for range groupModes {
+		mode = nextGroupMode(mode, monorepo)
+		cycle = append(cycle, mode)
+	}
+
+	expected := []string{GroupSource, GroupWorkspace, GroupPrefix, GroupNone}
+	if !reflect.DeepEqual(cycle, expected) {
+		t.Errorf("cycle = %v, want %v", cycle, expected)
+	}
+
+	// A single workspace would only show one "(root)" header
+	single := monorepo[:1]
+	if got := nextGroupMode(GroupSource, single); got != GroupPrefix {
+		t.Errorf("nextGroupMode(source) with one workspace = %s, want %s", got, GroupPrefix)
+	}
+}
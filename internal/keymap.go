@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds the selector's key bindings. Keys that type a character (q, j, /)
+// only act in vim normal mode: while the filter has focus they are always typed.
+type KeyMap struct {
+	Up            key.Binding
+	Down          key.Binding
+	Select        key.Binding
+	EditArgs      key.Binding
+	TogglePin     key.Binding
+	ToggleHooks   key.Binding
+	Group         key.Binding
+	CollapseGroup key.Binding
+	Preview       key.Binding
+	ClearFilter   key.Binding // Also quits when the filter is already empty
+	Quit          key.Binding
+	NormalMode    key.Binding // Vim mode: stop typing into the filter
+	InsertMode    key.Binding // Vim mode: type into the filter again
+}
+
+// DefaultKeyMap returns the key bindings used when the config doesn't override them
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:            key.NewBinding(key.WithKeys("up", "ctrl+p", "k"), key.WithHelp("", "up")),
+		Down:          key.NewBinding(key.WithKeys("down", "ctrl+n", "j"), key.WithHelp("", "down")),
+		Select:        key.NewBinding(key.WithKeys("enter"), key.WithHelp("", "select")),
+		EditArgs:      key.NewBinding(key.WithKeys("tab"), key.WithHelp("", "edit args")),
+		TogglePin:     key.NewBinding(key.WithKeys("alt+p"), key.WithHelp("", "toggle pin")),
+		ToggleHooks:   key.NewBinding(key.WithKeys("alt+h"), key.WithHelp("", "toggle hooks")),
+		Group:         key.NewBinding(key.WithKeys("alt+g"), key.WithHelp("", "group")),
+		CollapseGroup: key.NewBinding(key.WithKeys("alt+c"), key.WithHelp("", "collapse group")),
+		Preview:       key.NewBinding(key.WithKeys("alt+v"), key.WithHelp("", "preview")),
+		// Multiple shortcuts for different terminal/platform preferences:
+		// - esc: universal
+		// - ctrl+u: standard terminal "clear line"
+		// - alt+backspace: may work as cmd+backspace on Mac
+		// - ctrl+backspace: works on some terminals
+		// - ctrl+w: standard "delete word backward"
+		ClearFilter: key.NewBinding(key.WithKeys("esc", "ctrl+u", "alt+backspace", "ctrl+backspace", "ctrl+w"), key.WithHelp("", "clear")),
+		Quit:        key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("", "quit")),
+		NormalMode:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("", "normal mode")),
+		InsertMode:  key.NewBinding(key.WithKeys("i", "/"), key.WithHelp("", "type")),
+	}
+}
+
+// actions returns the bindings by the name used in the config's "keys"
+func (k *KeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":            &k.Up,
+		"down":          &k.Down,
+		"select":        &k.Select,
+		"editArgs":      &k.EditArgs,
+		"togglePin":     &k.TogglePin,
+		"toggleHooks":   &k.ToggleHooks,
+		"group":         &k.Group,
+		"collapseGroup": &k.CollapseGroup,
+		"preview":       &k.Preview,
+		"clearFilter":   &k.ClearFilter,
+		"quit":          &k.Quit,
+		"normalMode":    &k.NormalMode,
+		"insertMode":    &k.InsertMode,
+	}
+}
+
+// KeyMapFromConfig applies the config's "keys" overrides to the default bindings.
+// Invalid overrides are reported and the defaults kept for them.
+func KeyMapFromConfig(cfg *Config) (KeyMap, error) {
+	keys := DefaultKeyMap()
+	if cfg == nil {
+		return keys, nil
+	}
+
+	names := make([]string, 0, len(cfg.Keys))
+	for name := range cfg.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	actions := keys.actions()
+	for _, name := range names {
+		binding, ok := actions[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key binding action '%s'", name))
+			continue
+		}
+		overrides := cfg.Keys[name]
+		if binding == &keys.Quit && !cfg.VimMode && !hasNonTypingKey(overrides) {
+			errs = append(errs, fmt.Errorf("quit needs a key that doesn't type into the filter, like ctrl+c"))
+			continue
+		}
+		if len(overrides) == 0 {
+			binding.Unbind()
+			continue
+		}
+		binding.SetKeys(overrides...)
+	}
+
+	return keys, errors.Join(errs...)
+}
+
+// isTypingKey reports whether a key types a character into the filter
+func isTypingKey(k string) bool {
+	return utf8.RuneCountInString(k) == 1 // Letters, digits, punctuation and " " (space)
+}
+
+// hasNonTypingKey reports whether any of keys works while typing into the filter
+func hasNonTypingKey(keys []string) bool {
+	for _, k := range keys {
+		if !isTypingKey(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// inputKeys returns the bindings active while typing into the filter. Keys that type
+// a character are left out so the filter always receives them, and in vim mode the
+// keys going back to normal mode (esc) do nothing else.
+func (k KeyMap) inputKeys(vim bool) KeyMap {
+	k.InsertMode.Unbind()
+	if !vim {
+		k.NormalMode.Unbind()
+	}
+	normalMode := k.NormalMode
+	k.forEach(func(binding *key.Binding) {
+		var keys []string
+		for _, bound := range binding.Keys() {
+			if !isTypingKey(bound) && (binding == &k.NormalMode || !slices.Contains(normalMode.Keys(), bound)) {
+				keys = append(keys, bound)
+			}
+		}
+		binding.SetKeys(keys...)
+	})
+	return k.withHelp()
+}
+
+// normalKeys returns the bindings active in vim normal mode, where nothing is typed
+func (k KeyMap) normalKeys() KeyMap {
+	k.NormalMode.Unbind()
+	return k.withHelp()
+}
+
+// pickerKeys returns the bindings that apply to the --projects picker, where enter
+// opens the selected project and there are no scripts to pin, group or preview
+func (k KeyMap) pickerKeys() KeyMap {
+	for _, binding := range []*key.Binding{&k.EditArgs, &k.TogglePin, &k.ToggleHooks, &k.Group, &k.CollapseGroup, &k.Preview} {
+		binding.Unbind()
+	}
+	k.Select.SetHelp(k.Select.Help().Key, "open")
+	return k
+}
+
+// forEach calls fn with every binding
+func (k *KeyMap) forEach(fn func(binding *key.Binding)) {
+	for _, binding := range k.actions() {
+		fn(binding)
+	}
+}
+
+// withHelp shows each binding's first key in the help line (bindings left without
+// keys are disabled, and hidden from it)
+func (k KeyMap) withHelp() KeyMap {
+	k.forEach(func(binding *key.Binding) {
+		if keys := binding.Keys(); len(keys) > 0 {
+			binding.SetHelp(helpKey(keys[0]), binding.Help().Desc)
+		}
+	})
+	return k
+}
+
+// helpKey displays a key the way the help line shows it: ↑ for up, alt-p for alt+p
+func helpKey(k string) string {
+	switch k {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	}
+	return strings.Replace(k, "alt+", "alt-", 1)
+}
+
+// ShortHelp lists the bindings shown in the selector's help line
+func (k KeyMap) ShortHelp() []key.Binding {
+	navigate := key.NewBinding(key.WithKeys(k.Up.Keys()...), key.WithHelp(k.Up.Help().Key+"/"+k.Down.Help().Key, "navigate"))
+	navigate.SetEnabled(k.Down.Enabled())
+	return []key.Binding{navigate, k.Select, k.EditArgs, k.TogglePin, k.ToggleHooks, k.Group,
+		k.CollapseGroup, k.Preview, k.InsertMode, k.NormalMode, k.ClearFilter, k.Quit}
+}
+
+// FullHelp implements help.KeyMap; the selector only shows the short help
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyMapFromConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Keys = map[string][]string{
+		"togglePin": {"alt+p", "ctrl+t"},
+		"preview":   {},
+		"launch":    {"ctrl+l"},
+		"quit":      {"x"},
+	}
+
+	keys, err := KeyMapFromConfig(cfg)
+	if err == nil {
+		t.Error("expected errors for the unknown action and a quit key that types")
+	}
+
+	if got := keys.TogglePin.Keys(); !reflect.DeepEqual(got, []string{"alt+p", "ctrl+t"}) {
+		t.Errorf("togglePin keys = %v, want [alt+p ctrl+t]", got)
+	}
+	if keys.Preview.Enabled() {
+		t.Error("an empty list should unbind preview")
+	}
+	if got, want := keys.Quit.Keys(), DefaultKeyMap().Quit.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("quit keys = %v, want the defaults %v", got, want)
+	}
+
+	// In vim mode normal mode can quit with any key
+	cfg.VimMode = true
+	cfg.Keys = map[string][]string{"quit": {"x"}}
+	if keys, err = KeyMapFromConfig(cfg); err != nil {
+		t.Errorf("KeyMapFromConfig() error = %v", err)
+	}
+	if got := keys.Quit.Keys(); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("quit keys = %v, want [x]", got)
+	}
+}
+
+func TestInputKeysLeaveTypingToTheFilter(t *testing.T) {
+	keys := DefaultKeyMap()
+	keys.Select.SetKeys("enter", "o")
+
+	typed := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("q")},
+		{Type: tea.KeyRunes, Runes: []rune("j")},
+		{Type: tea.KeyRunes, Runes: []rune("o")},
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+	}
+	for _, vim := range []bool{false, true} {
+		input := keys.inputKeys(vim)
+		for _, msg := range typed {
+			if bindings := input.ShortHelp(); key.Matches(msg, bindings...) {
+				t.Errorf("inputKeys(vim=%v) acts on %q instead of typing it", vim, msg.String())
+			}
+		}
+	}
+
+	// Normal mode has no filter to type into
+	normal := keys.normalKeys()
+	if !key.Matches(typed[0], normal.Quit) || !key.Matches(typed[1], normal.Down) || !key.Matches(typed[2], normal.Select) {
+		t.Error("normalKeys() should act on q, j and o")
+	}
+
+	// While typing in vim mode, esc only goes back to normal mode
+	input := keys.inputKeys(true)
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+	if !key.Matches(esc, input.NormalMode) || key.Matches(esc, input.ClearFilter) {
+		t.Error("esc should switch to normal mode without clearing the filter")
+	}
+	if key.Matches(esc, keys.inputKeys(false).NormalMode) {
+		t.Error("normal mode should be off without vimMode")
+	}
+}
+
+func TestProjectPickerUsesKeyMap(t *testing.T) {
+	keys := DefaultKeyMap().pickerKeys()
+	keys.Down.SetKeys("down", "ctrl+j", "j")
+	projects := []ProjectSummary{
+		{Project: Project{Directory: "/code/web"}},
+		{Project: Project{Directory: "/code/api"}},
+	}
+
+	// Vim mode starts in normal mode, where j moves and nothing is typed
+	picker := &projectPicker{filter: textinput.New(), projects: projects, inputKeys: keys.inputKeys(true), normalKeys: keys.normalKeys()}
+	picker.setTyping(false)
+	picker.filterProjects()
+	picker.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if picker.selected != 1 || picker.filter.Value() != "" {
+		t.Errorf("j should move down in normal mode, got selection %d and filter %q", picker.selected, picker.filter.Value())
+	}
+
+	// Typing: the remapped ctrl+j moves, j goes to the filter
+	picker.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	picker.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	picker.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if !picker.typing || picker.filter.Value() != "j" {
+		t.Errorf("expected j typed into the filter, got %q", picker.filter.Value())
+	}
+
+	// Scripts can't be pinned or previewed from the picker
+	if keys.TogglePin.Enabled() || keys.Preview.Enabled() || keys.Select.Help().Desc != "open" {
+		t.Error("pickerKeys() should only keep the bindings the picker uses")
+	}
+}
+
+func TestProjectPickerInlineHeight(t *testing.T) {
+	projects := make([]ProjectSummary, 30)
+	picker := &projectPicker{filter: textinput.New(), projects: projects, inline: SelectorHeight{Lines: minInlineHeight}}
+	picker.filterProjects()
+	picker.Update(tea.WindowSizeMsg{Width: 80, Height: 50})
+
+	if picker.height != minInlineHeight {
+		t.Fatalf("height = %d, want %d", picker.height, minInlineHeight)
+	}
+	if lines := strings.Count(picker.View(), "\n") + 1; lines > minInlineHeight {
+		t.Errorf("inline picker is %d lines, taller than %d", lines, minInlineHeight)
+	}
+}
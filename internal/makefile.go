@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,12 +11,67 @@ import (
 
 // MakeTarget represents a Makefile target
 type MakeTarget struct {
-	Name    string
-	Command string
+	Name          string
+	Command       string
+	Prerequisites []string       // Targets/files listed after the colon
+	Description   string         // From "target: ## text" or comment lines directly above the target
+	Line          int            // 1-based line number of the target definition
+	Variables     []MakeVariable // Overridable variables the recipe reads, in order of first use
+}
+
+// MakeVariable is a Makefile variable that can be overridden with "make target VAR=value"
+type MakeVariable struct {
+	Name        string
+	Default     string // Value from "VAR ?= value", empty if the Makefile doesn't define it
+	Conditional bool   // Defined with "?=" (otherwise the Makefile never defines it)
+}
+
+// Regex to match target definitions: "targetname:" or "targetname: dependencies"
+var makeTargetRegex = regexp.MustCompile(`^([a-zA-Z0-9_-]+):\s*(.*)$`)
+
+// Regex to match variable assignments: "VAR ?= value", "export VAR := value", ...
+var makeAssignmentRegex = regexp.MustCompile(`^(?:export\s+|override\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*(\?=|::=|:=|\+=|!=|=)\s*(.*)$`)
+
+// Regex to match variable references in a recipe: "$(VAR)" or "${VAR}", but not "$$(cmd)"
+var makeVariableRefRegex = regexp.MustCompile(`(^|[^$])\$[({]([A-Za-z_][A-Za-z0-9_]*)[)}]`)
+
+// Variables make sets itself or uses in its implicit rules, which aren't parameters
+// of a particular target
+var builtinMakeVariables = map[string]bool{
+	"MAKE": true, "MAKEFLAGS": true, "MFLAGS": true, "MAKECMDGOALS": true, "MAKEFILE_LIST": true,
+	"MAKEFILES": true, "MAKELEVEL": true, "MAKEOVERRIDES": true, "MAKE_VERSION": true, "MAKE_HOST": true,
+	"MAKE_RESTARTS": true, "MAKE_TERMOUT": true, "MAKE_TERMERR": true, "CURDIR": true, "SHELL": true,
+	"VPATH": true, "SUFFIXES": true, "OUTPUT_OPTION": true,
+	// Programs and flags of the implicit rules
+	"AR": true, "AS": true, "CC": true, "CXX": true, "CPP": true, "FC": true, "M2C": true, "PC": true,
+	"CO": true, "GET": true, "LEX": true, "YACC": true, "LINT": true, "MAKEINFO": true, "TEX": true,
+	"TEXI2DVI": true, "WEAVE": true, "CWEAVE": true, "TANGLE": true, "CTANGLE": true, "RM": true, "LD": true,
+	"ARFLAGS": true, "ASFLAGS": true, "CFLAGS": true, "CXXFLAGS": true, "COFLAGS": true, "CPPFLAGS": true,
+	"FFLAGS": true, "GFLAGS": true, "LDFLAGS": true, "LDLIBS": true, "LFLAGS": true, "YFLAGS": true,
+	"PFLAGS": true, "RFLAGS": true, "LINTFLAGS": true,
 }
 
 // ReadMakefile reads and parses targets from a Makefile
+// Targets without a recipe (e.g. "all: build test") are skipped
 func ReadMakefile(directory string) ([]MakeTarget, error) {
+	allTargets, err := ReadAllMakefileTargets(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []MakeTarget
+	for _, target := range allTargets {
+		if target.Command != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+// ReadAllMakefileTargets reads every target from a Makefile, including
+// aggregate targets that only declare prerequisites
+func ReadAllMakefileTargets(directory string) ([]MakeTarget, error) {
 	makefilePath := filepath.Join(directory, "Makefile")
 
 	file, err := os.Open(makefilePath)
@@ -24,24 +80,57 @@ func ReadMakefile(directory string) ([]MakeTarget, error) {
 	}
 	defer file.Close()
 
-	var targets []MakeTarget
-	scanner := bufio.NewScanner(file)
+	return ParseMakefile(file)
+}
 
-	// Regex to match target definitions: "targetname:" or "targetname: dependencies"
-	targetRegex := regexp.MustCompile(`^([a-zA-Z0-9_-]+):\s*(.*)$`)
+// ParseMakefile parses all targets from Makefile content
+func ParseMakefile(r io.Reader) ([]MakeTarget, error) {
+	var targets []MakeTarget
+	scanner := bufio.NewScanner(r)
 
 	var currentTarget *MakeTarget
+	var commentLines []string // Comment block directly above the current line
+	lineNumber := 0
+
+	conditionalDefaults := make(map[string]string) // Variables defined with ?=
+	fixedVariables := make(map[string]bool)        // Variables defined with =, := etc.
 
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
+
+		// Remember comments in case they document the next target
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(line, "\t") {
+			commentLines = append(commentLines, strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+			continue
+		}
 
 		// Skip comments and empty lines
 		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.TrimSpace(line) == "" {
+			commentLines = nil
+			continue
+		}
+
+		// ".PHONY: build" often sits between a target's comment and the target itself
+		if strings.HasPrefix(line, ".PHONY") {
+			continue
+		}
+
+		// Variable assignments define parameters (?=) or internal values (=, :=)
+		if matches := makeAssignmentRegex.FindStringSubmatch(line); matches != nil && !strings.HasPrefix(line, "\t") {
+			if matches[2] == "?=" {
+				if _, defined := conditionalDefaults[matches[1]]; !defined {
+					conditionalDefaults[matches[1]] = strings.TrimSpace(matches[3])
+				}
+			} else {
+				fixedVariables[matches[1]] = true
+			}
+			commentLines = nil
 			continue
 		}
 
 		// Check if this is a target definition
-		if matches := targetRegex.FindStringSubmatch(line); matches != nil {
+		if matches := makeTargetRegex.FindStringSubmatch(line); matches != nil && !strings.HasPrefix(matches[2], "=") {
 			targetName := matches[1]
 
 			// Skip special targets like .PHONY
@@ -50,14 +139,22 @@ func ReadMakefile(directory string) ([]MakeTarget, error) {
 			}
 
 			// Save previous target if exists
-			if currentTarget != nil && currentTarget.Command != "" {
+			if currentTarget != nil {
 				targets = append(targets, *currentTarget)
 			}
 
 			// Start new target
 			currentTarget = &MakeTarget{
-				Name:    targetName,
-				Command: "",
+				Name:          targetName,
+				Command:       "",
+				Prerequisites: parsePrerequisites(matches[2]),
+				Description:   strings.Join(commentLines, " "),
+				Line:          lineNumber,
+			}
+
+			// Inline "## description" (self-documenting Makefile convention) wins
+			if idx := strings.Index(matches[2], "##"); idx >= 0 {
+				currentTarget.Description = strings.TrimSpace(matches[2][idx+2:])
 			}
 		} else if currentTarget != nil && strings.HasPrefix(line, "\t") {
 			// This is a command line (starts with tab)
@@ -71,10 +168,11 @@ func ReadMakefile(directory string) ([]MakeTarget, error) {
 				currentTarget.Command = command
 			}
 		}
+		commentLines = nil
 	}
 
 	// Add last target
-	if currentTarget != nil && currentTarget.Command != "" {
+	if currentTarget != nil {
 		targets = append(targets, *currentTarget)
 	}
 
@@ -82,9 +180,70 @@ func ReadMakefile(directory string) ([]MakeTarget, error) {
 		return nil, err
 	}
 
+	// Resolve which referenced variables can be overridden now that every assignment is known
+	for i := range targets {
+		targets[i].Variables = recipeVariables(targets[i], conditionalDefaults, fixedVariables)
+	}
+
 	return targets, nil
 }
 
+// recipeVariables returns the variables a recipe reads that are meant to be overridden:
+// those defined with "?=" and those the Makefile never defines. Variables make provides
+// itself ($(MAKE), $(CFLAGS)) and those set with "=" or ":=" aren't parameters.
+func recipeVariables(target MakeTarget, conditionalDefaults map[string]string, fixedVariables map[string]bool) []MakeVariable {
+	var variables []MakeVariable
+	seen := make(map[string]bool)
+
+	for _, match := range makeVariableRefRegex.FindAllStringSubmatch(target.Command, -1) {
+		name := match[2]
+		if seen[name] || builtinMakeVariables[name] || fixedVariables[name] {
+			continue
+		}
+		seen[name] = true
+
+		defaultValue, conditional := conditionalDefaults[name]
+		variables = append(variables, MakeVariable{Name: name, Default: defaultValue, Conditional: conditional})
+	}
+
+	return variables
+}
+
+// ResolveMakeVariables applies the environment to a target's variables the way make
+// will when it runs: an environment value replaces a "?=" default, and a variable the
+// Makefile never defines isn't asked for when the environment already sets it ($(HOME))
+func ResolveMakeVariables(variables []MakeVariable) []MakeVariable {
+	var resolved []MakeVariable
+	for _, variable := range variables {
+		if value, ok := os.LookupEnv(variable.Name); ok {
+			if !variable.Conditional {
+				continue
+			}
+			variable.Default = value
+		}
+		resolved = append(resolved, variable)
+	}
+	return resolved
+}
+
+// parsePrerequisites extracts prerequisite names from the text after a target's colon
+func parsePrerequisites(text string) []string {
+	// Drop trailing comments and inline recipes ("target: deps ; command")
+	if idx := strings.IndexAny(text, "#;"); idx >= 0 {
+		text = text[:idx]
+	}
+
+	var prerequisites []string
+	for _, field := range strings.Fields(text) {
+		// "|" separates normal from order-only prerequisites; both still run first
+		if field == "|" {
+			continue
+		}
+		prerequisites = append(prerequisites, field)
+	}
+	return prerequisites
+}
+
 // MakefileExists checks if a Makefile exists in the directory
 func MakefileExists(directory string) bool {
 	makefilePath := filepath.Join(directory, "Makefile")
@@ -0,0 +1,377 @@
+package runner
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDatabaseTooNew is returned when the database was migrated by a newer alex-runner
+var ErrDatabaseTooNew = errors.New("database was created by a newer version of alex-runner")
+
+// migration upgrades the schema by one version
+// Each migration runs in its own transaction together with its schema_version row
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// schemaMigrations lists every schema change in order. Never edit or reorder a released
+// migration; append a new one instead.
+var schemaMigrations = []migration{
+	{version: 1, description: "script usage and package manager cache", up: migrateInitialSchema},
+	{version: 2, description: "script run history", up: migrateScriptRuns},
+	{version: 3, description: "remembered Makefile variables", up: migrateMakeVariables},
+	{version: 4, description: "project identities", up: migrateProjects},
+	{version: 5, description: "maintenance metadata", up: migrateMetadata},
+	{version: 6, description: "branch and changed files of runs", up: migrateRunContext},
+	{version: 7, description: "every checkout of a project", up: migrateProjectDirectories},
+}
+
+// cacheMigrations set up the separate cache database. Caches are disposable, so a
+// broken cache file can simply be deleted.
+var cacheMigrations = []migration{
+	{version: 1, description: "package manager cache", up: migratePackageManagerCache},
+}
+
+// latestSchemaVersion returns the version the database ends up at after all migrations
+func latestSchemaVersion(migrations []migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// migrateDatabase brings the database up to the latest schema version, backing up
+// the file first if it already holds data
+func migrateDatabase(db *sql.DB, dbPath string, migrations []migration) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := currentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest := latestSchemaVersion(migrations)
+	if current > latest {
+		return fmt.Errorf("%w: %s is at schema version %d, this binary supports up to %d; please upgrade alex-runner",
+			ErrDatabaseTooNew, dbPath, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	// Databases from before versioning have tables but no schema_version rows
+	hasData, err := tableExists(db, "script_usage")
+	if err != nil {
+		return err
+	}
+	if current > 0 || hasData {
+		if err := backupDatabase(db, fmt.Sprintf("%s.v%d.bak", dbPath, current)); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currentSchemaVersion returns the highest applied migration, or 0 for a new database
+func currentSchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// applyMigration runs a single migration and records it, rolling back on failure
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	// Another process may have applied it while we waited for the write lock
+	var applied int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_version WHERE version = ?`, m.version).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to check migration %d: %w", m.version, err)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.description, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// backupSequence keeps temporary backup names unique within a process
+var backupSequence atomic.Int64
+
+// backupDatabase writes a consistent copy of the database to backupPath, replacing an older backup.
+// The copy goes to a temporary file first, since several connections may start migrating at once.
+func backupDatabase(db *sql.DB, backupPath string) error {
+	tmpPath := fmt.Sprintf("%s.%d-%d.tmp", backupPath, os.Getpid(), backupSequence.Add(1))
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old backup: %w", err)
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+	if err := os.Rename(tmpPath, backupPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+	return nil
+}
+
+// tableExists reports whether a table is present in the database
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// dropTableIfExists drops a table, checking first so that opening a database that
+// doesn't have it never needs a write lock
+func dropTableIfExists(db *sql.DB, table string) error {
+	exists, err := tableExists(db, table)
+	if err != nil || !exists {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, table)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", table, err)
+	}
+	return nil
+}
+
+// tableColumns returns the column names of a table (empty if it doesn't exist)
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan column of %s: %w", table, err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// migrateInitialSchema creates the original tables. Databases from before versioning
+// may lack the source/is_pinned columns and still have UNIQUE(directory, script_name),
+// so an existing script_usage table is rebuilt with the current shape.
+func migrateInitialSchema(tx *sql.Tx) error {
+	columns, err := tableColumns(tx, "script_usage")
+	if err != nil {
+		return err
+	}
+
+	createUsage := `
+	CREATE TABLE %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		directory TEXT NOT NULL,
+		script_name TEXT NOT NULL,
+		source TEXT DEFAULT '',
+		last_used TIMESTAMP NOT NULL,
+		use_count INTEGER DEFAULT 1,
+		is_pinned INTEGER DEFAULT 0,
+		UNIQUE(directory, script_name, source)
+	)`
+
+	if len(columns) == 0 {
+		if _, err := tx.Exec(fmt.Sprintf(createUsage, "script_usage")); err != nil {
+			return fmt.Errorf("failed to create script_usage: %w", err)
+		}
+	} else {
+		source := "''"
+		if columns["source"] {
+			source = "COALESCE(source, '')"
+		}
+		isPinned := "0"
+		if columns["is_pinned"] {
+			isPinned = "COALESCE(is_pinned, 0)"
+		}
+
+		statements := []string{
+			fmt.Sprintf(createUsage, "script_usage_new"),
+			fmt.Sprintf(`INSERT INTO script_usage_new (id, directory, script_name, source, last_used, use_count, is_pinned)
+			SELECT id, directory, script_name, %s, last_used, use_count, %s FROM script_usage`, source, isPinned),
+			`DROP TABLE script_usage`,
+			`ALTER TABLE script_usage_new RENAME TO script_usage`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to rebuild script_usage: %w", err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+	CREATE INDEX IF NOT EXISTS idx_directory ON script_usage(directory);
+	CREATE INDEX IF NOT EXISTS idx_frecency ON script_usage(directory, last_used DESC, use_count DESC);
+
+	CREATE TABLE IF NOT EXISTS package_manager_cache (
+		directory TEXT PRIMARY KEY,
+		package_manager TEXT NOT NULL,
+		detected_at TIMESTAMP NOT NULL
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes and cache table: %w", err)
+	}
+	return nil
+}
+
+// migratePackageManagerCache creates the package manager detection cache
+func migratePackageManagerCache(tx *sql.Tx) error {
+	_, err := tx.Exec(packageManagerCacheTable)
+	return err
+}
+
+// packageManagerCacheTable creates the package manager cache, in the cache database or,
+// with a single database file, in the state database
+const packageManagerCacheTable = `
+	CREATE TABLE IF NOT EXISTS package_manager_cache (
+		directory TEXT PRIMARY KEY,
+		package_manager TEXT NOT NULL,
+		detected_at TIMESTAMP NOT NULL
+	);
+	`
+
+// migrateScriptRuns adds the run history used by the preview pane and args prompt
+func migrateScriptRuns(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS script_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		directory TEXT NOT NULL,
+		script_name TEXT NOT NULL,
+		source TEXT DEFAULT '',
+		command TEXT DEFAULT '',
+		args TEXT DEFAULT '',
+		started_at TIMESTAMP NOT NULL,
+		duration_ms INTEGER DEFAULT 0,
+		exit_code INTEGER DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_runs_script ON script_runs(directory, script_name, source, started_at DESC);
+	`)
+	return err
+}
+
+// migrateMakeVariables adds the last values entered for Makefile variables
+func migrateMakeVariables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS make_variables (
+		directory TEXT NOT NULL,
+		target TEXT NOT NULL,
+		name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (directory, target, name)
+	);
+	`)
+	return err
+}
+
+// migrateProjects adds the last known directory of each project key, so history keyed
+// by git remote can still be found (and cleaned up) by path
+func migrateProjects(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS projects (
+		key TEXT PRIMARY KEY,
+		directory TEXT NOT NULL,
+		remote TEXT DEFAULT '',
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_projects_directory ON projects(directory);
+	`)
+	return err
+}
+
+// migrateMetadata adds a key/value table for bookkeeping such as the last garbage collection
+func migrateMetadata(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS metadata (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`)
+	return err
+}
+
+// migrateRunContext records the branch and changed files of each run, so scripts can
+// be ranked by how similar the current work is to when they were used before
+func migrateRunContext(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	ALTER TABLE script_runs ADD COLUMN branch TEXT DEFAULT '';
+	ALTER TABLE script_runs ADD COLUMN changed_paths TEXT DEFAULT '';
+	`)
+	return err
+}
+
+// migrateProjectDirectories remembers every directory a project key was seen in, not only
+// the last one, so removing one clone or worktree doesn't look like the project is gone
+func migrateProjectDirectories(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS project_directories (
+		key TEXT NOT NULL,
+		directory TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (key, directory)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_project_directories_directory ON project_directories(directory);
+
+	INSERT OR IGNORE INTO project_directories (key, directory, updated_at)
+	SELECT key, directory, updated_at FROM projects;
+	`)
+	return err
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateNewDatabase(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion() error = %v", err)
+	}
+	if version != latestSchemaVersion(schemaMigrations) {
+		t.Errorf("expected schema version %d, got %d", latestSchemaVersion(schemaMigrations), version)
+	}
+
+	for _, table := range []string{"script_usage", "package_manager_cache", "script_runs", "make_variables", "projects", "metadata"} {
+		exists, err := tableExists(db.db, table)
+		if err != nil || !exists {
+			t.Errorf("expected table %s to exist (err: %v)", table, err)
+		}
+	}
+
+	// Nothing to back up for a brand new database
+	if matches, _ := filepath.Glob(dbPath + ".v*.bak"); len(matches) != 0 {
+		t.Errorf("expected no backup for a new database, found %v", matches)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	if err := db.RecordUsage("/test", "dev", "npm"); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+	db.Close()
+
+	db2, err := InitDatabaseWithPath(dbPath)
+	if err != nil {
+		t.Fatalf("reopening database failed: %v", err)
+	}
+	defer db2.Close()
+
+	stats, _ := db2.GetUsageStats("/test")
+	if len(stats) != 1 {
+		t.Errorf("expected usage to survive reopening, got %d rows", len(stats))
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Schema as shipped before sources and pins existed
+	legacy, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`
+	CREATE TABLE script_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		directory TEXT NOT NULL,
+		script_name TEXT NOT NULL,
+		last_used TIMESTAMP NOT NULL,
+		use_count INTEGER DEFAULT 1,
+		UNIQUE(directory, script_name)
+	);
+	INSERT INTO script_usage (directory, script_name, last_used, use_count) VALUES ('/test', 'dev', ?, 7);
+	`, time.Now())
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	db, err := InitDatabaseWithPath(dbPath)
+	if err != nil {
+		t.Fatalf("migrating legacy database failed: %v", err)
+	}
+	defer db.Close()
+
+	stats, err := db.GetUsageStats("/test")
+	if err != nil {
+		t.Fatalf("GetUsageStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].UseCount != 7 || stats[0].Source != "" {
+		t.Fatalf("expected legacy row with 7 uses and empty source, got %+v", stats)
+	}
+
+	// The old UNIQUE(directory, script_name) would reject the same name from another source
+	if err := db.RecordUsage("/test", "dev", "make"); err != nil {
+		t.Fatalf("RecordUsage() with a new source failed after migration: %v", err)
+	}
+	if _, err := db.TogglePin("/test", "dev", "make"); err != nil {
+		t.Fatalf("TogglePin() failed after migration: %v", err)
+	}
+
+	if _, err := os.Stat(dbPath + ".v0.bak"); err != nil {
+		t.Errorf("expected a backup of the legacy database: %v", err)
+	}
+}
+
+func TestMigrateRejectsNewerDatabase(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	_, err := db.db.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES (999, 'from the future', ?)`, time.Now())
+	if err != nil {
+		t.Fatalf("failed to bump schema version: %v", err)
+	}
+	db.Close()
+
+	_, err = InitDatabaseWithPath(dbPath)
+	if !errors.Is(err, ErrDatabaseTooNew) {
+		t.Fatalf("expected ErrDatabaseTooNew, got %v", err)
+	}
+}
+
+func TestMigrationFailureRollsBack(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	conn, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer conn.Close()
+
+	migrations := []migration{
+		{version: 1, description: "ok", up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE first (id INTEGER)`)
+			return err
+		}},
+		{version: 2, description: "broken", up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE second (id INTEGER)`); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}},
+	}
+
+	if err := migrateDatabase(conn, dbPath, migrations); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+
+	version, _ := currentSchemaVersion(conn)
+	if version != 1 {
+		t.Errorf("expected schema version 1 after the failure, got %d", version)
+	}
+	if exists, _ := tableExists(conn, "second"); exists {
+		t.Error("expected the failed migration's table to be rolled back")
+	}
+}
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,15 +10,30 @@ import (
 	"strings"
 )
 
+// ErrNoScripts is returned for a package.json without scripts
+var ErrNoScripts = errors.New("no scripts found in package.json")
+
 type PackageJSON struct {
-	Name    string            `json:"name"`
-	Scripts map[string]string `json:"scripts"`
+	Name        string            `json:"name"`
+	Scripts     map[string]string `json:"scripts"`
+	ScriptsInfo map[string]string `json:"scripts-info"` // Script descriptions (scripts-info convention)
+	NTL         struct {
+		Descriptions map[string]string `json:"descriptions"` // Script descriptions (ntl convention)
+	} `json:"ntl"`
+
+	raw []byte // Original file content, used to locate script line numbers
 }
 
 type NPMScript struct {
-	Name    string
-	Command string
-	Source  string // "make", "npm", "yarn", "pnpm", etc.
+	Name        string
+	Command     string
+	Source      string // "make", "npm", "yarn", "pnpm", etc.
+	HookOf      string // Main script name if this is a pre<name>/post<name> lifecycle hook
+	Description string // Optional human description of the script
+	File        string // File the script is defined in ("package.json", "Makefile")
+	Line        int    // 1-based line of the definition (0 if unknown)
+
+	Variables []MakeVariable // Makefile variables the recipe reads, offered as parameters
 }
 
 // GetGitRoot returns the root of the git repository, or the current directory if not in a git repo
@@ -91,9 +107,10 @@ func ReadPackageJSON(directory string) (*PackageJSON, error) {
 	}
 
 	if len(pkg.Scripts) == 0 {
-		return nil, fmt.Errorf("no scripts found in package.json")
+		return nil, ErrNoScripts
 	}
 
+	pkg.raw = data
 	return &pkg, nil
 }
 
@@ -101,10 +118,61 @@ func GetScripts(pkg *PackageJSON) []NPMScript {
 	scripts := make([]NPMScript, 0, len(pkg.Scripts))
 	for name, command := range pkg.Scripts {
 		scripts = append(scripts, NPMScript{
-			Name:    name,
-			Command: command,
-			Source:  "", // Will be set by caller
+			Name:        name,
+			Command:     command,
+			Source:      "", // Will be set by caller
+			HookOf:      LifecycleHookOf(name, pkg.Scripts),
+			Description: scriptDescription(pkg, name),
+			File:        "package.json",
+			Line:        findScriptLine(pkg.raw, name),
 		})
 	}
 	return scripts
 }
+
+// scriptDescription looks up a script's description from "scripts-info" or "ntl.descriptions"
+func scriptDescription(pkg *PackageJSON, name string) string {
+	if description, ok := pkg.ScriptsInfo[name]; ok {
+		return description
+	}
+	return pkg.NTL.Descriptions[name]
+}
+
+// findScriptLine returns the 1-based line of a script inside the "scripts" object, or 0 if not found
+func findScriptLine(data []byte, name string) int {
+	content := string(data)
+	scriptsIdx := strings.Index(content, `"scripts"`)
+	if scriptsIdx < 0 {
+		return 0
+	}
+
+	key, err := json.Marshal(name)
+	if err != nil {
+		return 0
+	}
+	keyIdx := strings.Index(content[scriptsIdx:], string(key)+":")
+	if keyIdx < 0 {
+		// Allow whitespace between the key and the colon
+		keyIdx = strings.Index(content[scriptsIdx:], string(key)+" ")
+		if keyIdx < 0 {
+			return 0
+		}
+	}
+
+	return strings.Count(content[:scriptsIdx+keyIdx], "\n") + 1
+}
+
+// LifecycleHookOf returns the main script that a pre<name>/post<name> hook
+// belongs to, or "" if the script isn't a hook of an existing script
+func LifecycleHookOf(name string, scripts map[string]string) string {
+	for _, prefix := range []string{"pre", "post"} {
+		mainScript := strings.TrimPrefix(name, prefix)
+		if mainScript == name || mainScript == "" {
+			continue
+		}
+		if _, exists := scripts[mainScript]; exists {
+			return mainScript
+		}
+	}
+	return ""
+}
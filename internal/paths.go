@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appDirName         = "alex-runner"
+	databaseFileName   = "alex-runner.sqlite.db"
+	cacheFileName      = "cache.sqlite.db"
+	configFileName     = "config.json"
+	databasePathEnvVar = "ALEX_RUNNER_DB" // Overrides the usage history database location
+)
+
+// DatabasePaths says where usage history and disposable caches are stored
+type DatabasePaths struct {
+	State string // Usage history, pins, run history and remembered variables
+	Cache string // Package manager detection cache (safe to delete)
+}
+
+// xdgDir returns $<envVar>/alex-runner, or ~/<fallback>/alex-runner when the variable
+// is unset or not absolute (as the XDG Base Directory spec requires)
+func xdgDir(envVar string, fallback string) (string, error) {
+	if dir := os.Getenv(envVar); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDirName), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, fallback, appDirName), nil
+}
+
+// ConfigDir returns the directory holding config.json ($XDG_CONFIG_HOME/alex-runner)
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// StateDir returns the directory holding usage history ($XDG_STATE_HOME/alex-runner)
+func StateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// CacheDir returns the directory holding caches ($XDG_CACHE_HOME/alex-runner)
+func CacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// LegacyDatabasePath returns where versions before XDG support kept the database
+func LegacyDatabasePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", appDirName, databaseFileName), nil
+}
+
+// ResolveDatabasePaths picks the database locations. The usage history database comes
+// from override (the --db flag), then $ALEX_RUNNER_DB, then the XDG state directory.
+// isDefault reports whether the XDG default was used.
+func ResolveDatabasePaths(override string) (paths DatabasePaths, isDefault bool, err error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return DatabasePaths{}, false, err
+	}
+	paths.Cache = filepath.Join(cacheDir, cacheFileName)
+
+	switch {
+	case override != "":
+		paths.State = override
+	case os.Getenv(databasePathEnvVar) != "":
+		paths.State = os.Getenv(databasePathEnvVar)
+	default:
+		stateDir, err := StateDir()
+		if err != nil {
+			return DatabasePaths{}, false, err
+		}
+		paths.State = filepath.Join(stateDir, databaseFileName)
+		isDefault = true
+	}
+
+	return paths, isDefault, nil
+}
+
+// moveLegacyDatabase copies the database from its pre-XDG location to statePath the
+// first time the new location is used, then renames the old file to "<name>.migrated"
+// Returns true if data was moved. Concurrent first runs are safe: the copy is made
+// under a unique name and only linked (or, without hard links, renamed) into place if
+// statePath still doesn't exist.
+func moveLegacyDatabase(legacyPath string, statePath string) (bool, error) {
+	if legacyPath == statePath {
+		return false, nil
+	}
+	if _, err := os.Stat(statePath); err == nil {
+		return false, nil // Already using the new location
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return false, nil // Nothing to move
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// VACUUM INTO accepts an empty file, so this reserves a name nobody else uses
+	tmp, err := os.CreateTemp(filepath.Dir(statePath), filepath.Base(statePath)+".migrating-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary database: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer removeDatabaseFiles(tmpPath)
+
+	// VACUUM INTO produces a consistent copy including anything still in the WAL,
+	// and works across filesystems where a rename wouldn't
+	// mode=rw rather than creating an empty database if another run just moved it
+	legacy, err := sql.Open("sqlite", "file:"+legacyPath+"?mode=rw&"+sqliteConnectionParams)
+	if err != nil {
+		return false, fmt.Errorf("failed to open legacy database: %w", err)
+	}
+	_, err = legacy.Exec(`VACUUM INTO ?`, tmpPath)
+	legacy.Close()
+	if err != nil {
+		if _, statErr := os.Stat(legacyPath); statErr != nil {
+			return false, nil // Moved by another run in the meantime
+		}
+		return false, fmt.Errorf("failed to copy legacy database: %w", err)
+	}
+
+	// A hard link fails if another process has put its copy in place meanwhile
+	if err := linkFile(tmpPath, statePath); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+		// Filesystems without hard links: a rename would replace a copy that appeared
+		// in the meantime, so only the window between this check and the rename is racy
+		if _, statErr := os.Stat(statePath); statErr == nil {
+			return false, nil
+		}
+		if err := os.Rename(tmpPath, statePath); err != nil {
+			return false, fmt.Errorf("failed to move database to %s: %w", statePath, err)
+		}
+	}
+
+	if err := os.Rename(legacyPath, legacyPath+".migrated"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return true, fmt.Errorf("moved database to %s but failed to rename the old copy: %w", statePath, err)
+	}
+	return true, nil
+}
+
+// linkFile is os.Link, replaceable in tests to simulate filesystems without hard links
+var linkFile = os.Link
+
+// removeDatabaseFiles removes a SQLite database this process created, with its
+// journal files
+func removeDatabaseFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		os.Remove(path + suffix)
+	}
+}
@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestResolveDatabasePathsXDG(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(tmpDir, "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	t.Setenv(databasePathEnvVar, "")
+
+	paths, isDefault, err := ResolveDatabasePaths("")
+	if err != nil {
+		t.Fatalf("ResolveDatabasePaths() error = %v", err)
+	}
+
+	if !isDefault {
+		t.Error("expected the XDG default to be used")
+	}
+	if want := filepath.Join(tmpDir, "state", "alex-runner", "alex-runner.sqlite.db"); paths.State != want {
+		t.Errorf("State = %s, want %s", paths.State, want)
+	}
+	if want := filepath.Join(tmpDir, "cache", "alex-runner", "cache.sqlite.db"); paths.Cache != want {
+		t.Errorf("Cache = %s, want %s", paths.Cache, want)
+	}
+}
+
+func TestResolveDatabasePathsIgnoresRelativeXDG(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", "relative/state")
+	t.Setenv(databasePathEnvVar, "")
+
+	paths, _, err := ResolveDatabasePaths("")
+	if err != nil {
+		t.Fatalf("ResolveDatabasePaths() error = %v", err)
+	}
+	if want := filepath.Join(home, ".local", "state", "alex-runner", "alex-runner.sqlite.db"); paths.State != want {
+		t.Errorf("State = %s, want %s", paths.State, want)
+	}
+}
+
+func TestResolveDatabasePathsOverrides(t *testing.T) {
+	t.Setenv(databasePathEnvVar, "/from/env.db")
+
+	paths, isDefault, err := ResolveDatabasePaths("")
+	if err != nil {
+		t.Fatalf("ResolveDatabasePaths() error = %v", err)
+	}
+	if paths.State != "/from/env.db" || isDefault {
+		t.Errorf("expected $ALEX_RUNNER_DB to be used, got %s (default: %v)", paths.State, isDefault)
+	}
+
+	paths, _, _ = ResolveDatabasePaths("/from/flag.db")
+	if paths.State != "/from/flag.db" {
+		t.Errorf("expected --db to win over $ALEX_RUNNER_DB, got %s", paths.State)
+	}
+}
+
+func TestMoveLegacyDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacyPath := filepath.Join(tmpDir, "config", "alex-runner.sqlite.db")
+	statePath := filepath.Join(tmpDir, "state", "alex-runner", "alex-runner.sqlite.db")
+
+	if err := os.MkdirAll(filepath.Dir(legacyPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := InitDatabaseWithPath(legacyPath)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	if err := legacy.RecordUsage("/test", "dev", "npm"); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+	legacy.Close()
+
+	moved, err := moveLegacyDatabase(legacyPath, statePath)
+	if err != nil || !moved {
+		t.Fatalf("moveLegacyDatabase() = %v, %v; want true, nil", moved, err)
+	}
+
+	db, err := InitDatabaseWithPath(statePath)
+	if err != nil {
+		t.Fatalf("failed to open moved database: %v", err)
+	}
+	defer db.Close()
+
+	stats, _ := db.GetUsageStats("/test")
+	if len(stats) != 1 {
+		t.Errorf("expected usage history to be moved, got %d rows", len(stats))
+	}
+
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("expected the legacy database to be renamed")
+	}
+	if _, err := os.Stat(legacyPath + ".migrated"); err != nil {
+		t.Errorf("expected the legacy database to be kept as .migrated: %v", err)
+	}
+
+	// Second run: nothing left to move
+	moved, err = moveLegacyDatabase(legacyPath, statePath)
+	if err != nil || moved {
+		t.Errorf("second moveLegacyDatabase() = %v, %v; want false, nil", moved, err)
+	}
+}
+
+func TestMoveLegacyDatabaseConcurrently(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacyPath := filepath.Join(tmpDir, "config", "alex-runner.sqlite.db")
+	statePath := filepath.Join(tmpDir, "state", "alex-runner.sqlite.db")
+
+	if err := os.MkdirAll(filepath.Dir(legacyPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := InitDatabaseWithPath(legacyPath)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	if err := legacy.RecordUsage("/test", "dev", "npm"); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+	legacy.Close()
+
+	// Two first runs at once: one moves the database, neither loses it
+	var wg sync.WaitGroup
+	results := make([]bool, 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			moved, err := moveLegacyDatabase(legacyPath, statePath)
+			if err != nil {
+				t.Errorf("moveLegacyDatabase() error = %v", err)
+			}
+			results[i] = moved
+		}()
+	}
+	wg.Wait()
+
+	movedCount := 0
+	for _, moved := range results {
+		if moved {
+			movedCount++
+		}
+	}
+	if movedCount != 1 {
+		t.Errorf("expected exactly one run to move the database, got %d", movedCount)
+	}
+
+	db, err := InitDatabaseWithPath(statePath)
+	if err != nil {
+		t.Fatalf("failed to open moved database: %v", err)
+	}
+	defer db.Close()
+	if stats, _ := db.GetUsageStats("/test"); len(stats) != 1 {
+		t.Errorf("expected usage history to survive, got %d rows", len(stats))
+	}
+
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("expected the legacy database to be renamed, not recreated")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(statePath), "*.migrating-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary copies left behind: %v", leftovers)
+	}
+}
+
+func TestMoveLegacyDatabaseWithoutHardLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacyPath := filepath.Join(tmpDir, "config", "alex-runner.sqlite.db")
+	statePath := filepath.Join(tmpDir, "state", "alex-runner.sqlite.db")
+
+	if err := os.MkdirAll(filepath.Dir(legacyPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := InitDatabaseWithPath(legacyPath)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	if err := legacy.RecordUsage("/test", "dev", "npm"); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+	legacy.Close()
+
+	// Like link(2) on a filesystem that doesn't support hard links
+	linkFile = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+	}
+	defer func() { linkFile = os.Link }()
+
+	moved, err := moveLegacyDatabase(legacyPath, statePath)
+	if err != nil || !moved {
+		t.Fatalf("moveLegacyDatabase() = %v, %v; expected the database to be renamed into place", moved, err)
+	}
+
+	db, err := InitDatabaseWithPath(statePath)
+	if err != nil {
+		t.Fatalf("failed to open moved database: %v", err)
+	}
+	defer db.Close()
+	if stats, _ := db.GetUsageStats("/test"); len(stats) != 1 {
+		t.Errorf("expected usage history to survive, got %d rows", len(stats))
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(statePath), "*.migrating-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary copies left behind: %v", leftovers)
+	}
+}
+
+func TestOpenDatabaseSeparateCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := DatabasePaths{
+		State: filepath.Join(tmpDir, "state", "history.db"),
+		Cache: filepath.Join(tmpDir, "cache", "cache.db"),
+	}
+
+	db, err := OpenDatabase(paths)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetCachedPackageManager("/test", "pnpm"); err != nil {
+		t.Fatalf("SetCachedPackageManager() error = %v", err)
+	}
+	if pm, _ := db.GetCachedPackageManager("/test"); pm != "pnpm" {
+		t.Errorf("expected cached pnpm, got %q", pm)
+	}
+
+	var count int
+	if err := db.cache.QueryRow(`SELECT COUNT(*) FROM package_manager_cache`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("expected the cache entry in the cache database (count %d, err %v)", count, err)
+	}
+	if exists, _ := tableExists(db.cache, "script_usage"); exists {
+		t.Error("cache database should not hold usage history")
+	}
+	if exists, _ := tableExists(db.db, "package_manager_cache"); exists {
+		t.Error("state database should not keep the unused package manager cache")
+	}
+	db.Close()
+
+	// The same file opened on its own holds the cache again
+	single, err := InitDatabaseWithPath(paths.State)
+	if err != nil {
+		t.Fatalf("InitDatabaseWithPath() error = %v", err)
+	}
+	defer single.Close()
+	if err := single.SetCachedPackageManager("/test", "npm"); err != nil {
+		t.Errorf("SetCachedPackageManager() on a single file error = %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package runner
+
+import (
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const gitProjectKeyPrefix = "git:"
+
+// Project is the identity usage history is stored under. Checkouts of the same git
+// remote share history wherever they live on disk, including worktrees and renamed folders.
+type Project struct {
+	Key       string // History key: "git:<remote>[//<path in repo>]", or the absolute directory
+	Directory string // Absolute directory the project was resolved from
+	Remote    string // Normalized remote URL (empty outside git checkouts with a remote)
+	RepoPath  string // Directory relative to the repository root (empty at the root)
+}
+
+// ResolveProject derives the project identity for a directory from its git remote and
+// the path within the repository, falling back to the directory itself
+func ResolveProject(directory string) Project {
+	project := Project{Key: directory, Directory: directory}
+
+	root, err := gitOutput(directory, "rev-parse", "--show-toplevel")
+	if err != nil || root == "" {
+		return project
+	}
+	remote := NormalizeRemoteURL(gitRemoteURL(directory))
+	if remote == "" {
+		return project // Local-only repository: the path is all we have
+	}
+
+	// git reports the root with symlinks resolved, so resolve ours too before comparing
+	resolved := directory
+	if evaluated, err := filepath.EvalSymlinks(directory); err == nil {
+		resolved = evaluated
+	}
+	relPath, err := filepath.Rel(root, resolved)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return project
+	}
+
+	project.Remote = remote
+	project.Key = gitProjectKeyPrefix + remote
+	if relPath != "." {
+		project.RepoPath = filepath.ToSlash(relPath)
+		project.Key += "//" + project.RepoPath
+	}
+	return project
+}
+
+// gitOutput runs a git command in directory and returns its trimmed output
+func gitOutput(directory string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = directory
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitRemoteURL returns the URL of "origin", or of the first remote if there is no origin
+func gitRemoteURL(directory string) string {
+	output, err := gitOutput(directory, "config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		return ""
+	}
+
+	first := ""
+	for _, line := range strings.Split(output, "\n") {
+		name, url, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if name == "remote.origin.url" {
+			return url
+		}
+		if first == "" {
+			first = url
+		}
+	}
+	return first
+}
+
+// NormalizeRemoteURL reduces the ways of writing a remote to "host/owner/repo", so
+// git@github.com:owner/repo.git and https://github.com/owner/repo are the same project
+func NormalizeRemoteURL(raw string) string {
+	url := strings.TrimSpace(raw)
+	if url == "" {
+		return ""
+	}
+
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+		if scheme == "file" {
+			return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+		}
+		// Drop credentials and ports
+		if at := strings.LastIndex(strings.SplitN(url, "/", 2)[0], "@"); at >= 0 {
+			url = url[at+1:]
+		}
+		host, path, _ := strings.Cut(url, "/")
+		host, _, _ = strings.Cut(host, ":")
+		url = host + "/" + path
+	} else if host, path, ok := strings.Cut(url, ":"); ok && !strings.Contains(host, "/") {
+		// scp-like syntax: [user@]host:owner/repo
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		url = host + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	host, path, _ := strings.Cut(url, "/")
+	if path == "" {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(host) + "/" + path
+}
+
+// RegisterProject remembers where a project was last seen and adopts history recorded
+// under its plain directory path (before project identities, or before a remote was added).
+// Returns the number of scripts whose history was adopted.
+func (d *Database) RegisterProject(project Project) (int, error) {
+	var directory string
+	err := d.db.QueryRow(`SELECT directory FROM projects WHERE key = ?`, project.Key).Scan(&directory)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up project: %w", err)
+	}
+	if err == nil && directory == project.Directory && !d.hasHistory(project.Directory, project.Key) {
+		return 0, nil // Nothing changed; avoid a write on every run
+	}
+
+	adopted := 0
+	err = d.withTx(func(tx *sql.Tx) error {
+		now := time.Now()
+		_, err := tx.Exec(`
+		INSERT INTO projects (key, directory, remote, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET directory = excluded.directory, remote = excluded.remote, updated_at = excluded.updated_at
+		`, project.Key, project.Directory, project.Remote, now)
+		if err != nil {
+			return fmt.Errorf("failed to record project: %w", err)
+		}
+		_, err = tx.Exec(`
+		INSERT INTO project_directories (key, directory, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key, directory) DO UPDATE SET updated_at = excluded.updated_at
+		`, project.Key, project.Directory, now)
+		if err != nil {
+			return fmt.Errorf("failed to record project directory: %w", err)
+		}
+
+		if project.Key == project.Directory {
+			return nil
+		}
+		report, err := mergeHistory(tx, project.Directory, project.Key)
+		adopted = report.Usage
+		return err
+	})
+	return adopted, err
+}
+
+// CachedProject returns the project last registered for directory without running
+// git, for callers that run often and only read history (shell completion). A remote
+// changed since then is picked up by the next regular run.
+func (d *Database) CachedProject(directory string) (Project, bool) {
+	project := Project{Directory: directory}
+	err := d.db.QueryRow(`
+	SELECT c.key, COALESCE(p.remote, '')
+	FROM project_directories c
+	LEFT JOIN projects p ON p.key = c.key
+	WHERE c.directory = ?
+	ORDER BY c.updated_at DESC
+	LIMIT 1
+	`, directory).Scan(&project.Key, &project.Remote)
+	if err != nil {
+		return Project{}, false
+	}
+	if rest, ok := strings.CutPrefix(project.Key, gitProjectKeyPrefix); ok {
+		_, project.RepoPath, _ = strings.Cut(rest, "//")
+	}
+	return project, true
+}
+
+// hasHistory reports whether usage is still stored under directory for a project keyed differently
+func (d *Database) hasHistory(directory string, key string) bool {
+	if directory == key {
+		return false
+	}
+	var count int
+	d.db.QueryRow(`SELECT COUNT(*) FROM script_usage WHERE directory = ?`, directory).Scan(&count)
+	return count > 0
+}
+
+// HistoryKeysForDirectory returns the history keys that were used for a directory: the
+// path itself and any project seen there. The directory doesn't need to exist anymore.
+func (d *Database) HistoryKeysForDirectory(directory string) ([]string, error) {
+	keys := []string{directory}
+
+	rows, err := d.db.Query(`SELECT key FROM project_directories WHERE directory = ? AND key != ? ORDER BY updated_at DESC`, directory, directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up projects: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// MergeHistory moves usage, pins, runs and remembered variables from one history key
+// into another, adding run counts together
+func (d *Database) MergeHistory(fromKey string, toKey string) (ImportReport, error) {
+	var report ImportReport
+	if fromKey == toKey {
+		return report, nil
+	}
+	err := d.withTx(func(tx *sql.Tx) error {
+		var err error
+		report, err = mergeHistory(tx, fromKey, toKey)
+		if err != nil {
+			return err
+		}
+		for _, query := range []string{
+			`DELETE FROM projects WHERE key = ?`,
+			`DELETE FROM project_directories WHERE key = ?`,
+		} {
+			if _, err := tx.Exec(query, fromKey); err != nil {
+				return fmt.Errorf("failed to remove merged project: %w", err)
+			}
+		}
+		return nil
+	})
+	return report, err
+}
+
+// mergeHistory re-keys all history rows inside a transaction, merging with existing rows
+func mergeHistory(tx *sql.Tx, fromKey string, toKey string) (ImportReport, error) {
+	var report ImportReport
+
+	var usages []ExportedUsage
+	rows, err := tx.Query(`SELECT script_name, COALESCE(source, ''), last_used, use_count, COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ?`, fromKey)
+	if err != nil {
+		return report, fmt.Errorf("failed to read usage to merge: %w", err)
+	}
+	for rows.Next() {
+		usage := ExportedUsage{Directory: toKey}
+		var isPinned int
+		if err := rows.Scan(&usage.ScriptName, &usage.Source, &usage.LastUsed, &usage.UseCount, &isPinned); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan usage to merge: %w", err)
+		}
+		usage.IsPinned = isPinned != 0
+		usages = append(usages, usage)
+	}
+	rows.Close()
+
+	for _, usage := range usages {
+		if err := importUsage(tx, usage, MergeSum); err != nil {
+			return report, err
+		}
+		report.Usage++
+	}
+
+	var variables []ExportedMakeVariable
+	rows, err = tx.Query(`SELECT target, name, value, updated_at FROM make_variables WHERE directory = ?`, fromKey)
+	if err != nil {
+		return report, fmt.Errorf("failed to read make variables to merge: %w", err)
+	}
+	for rows.Next() {
+		variable := ExportedMakeVariable{Directory: toKey}
+		if err := rows.Scan(&variable.Target, &variable.Name, &variable.Value, &variable.UpdatedAt); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan make variable to merge: %w", err)
+		}
+		variables = append(variables, variable)
+	}
+	rows.Close()
+
+	for _, variable := range variables {
+		if err := importMakeVariable(tx, variable, MergeSum); err != nil {
+			return report, err
+		}
+		report.MakeVariables++
+	}
+
+	// Runs are a log, so they can simply be re-keyed
+	result, err := tx.Exec(`UPDATE script_runs SET directory = ? WHERE directory = ?`, toKey, fromKey)
+	if err != nil {
+		return report, fmt.Errorf("failed to merge run history: %w", err)
+	}
+	runs, _ := result.RowsAffected()
+	report.Runs = int(runs)
+
+	for _, query := range []string{
+		`DELETE FROM script_usage WHERE directory = ?`,
+		`DELETE FROM make_variables WHERE directory = ?`,
+	} {
+		if _, err := tx.Exec(query, fromKey); err != nil {
+			return report, fmt.Errorf("failed to remove merged history: %w", err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,291 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// Project picker sizing
+const (
+	projectPreviewScripts   = 5 // Top scripts shown for the selected project
+	projectPickerFixedLines = 8 // Title (2), filter (2), preview title (2), help (2)
+)
+
+// projectPicker is the --projects selector: known projects ranked by frecency, with
+// the top scripts of the selected one underneath
+type projectPicker struct {
+	filter     textinput.Model
+	projects   []ProjectSummary
+	filtered   []ProjectSummary
+	selected   int
+	offset     int // First visible project
+	width      int
+	height     int
+	inline     SelectorHeight // Drawn below the prompt instead of full screen
+	inputKeys  KeyMap         // Bindings while typing into the filter
+	normalKeys KeyMap         // Bindings in vim normal mode
+	typing     bool           // Keys go to the filter (always, unless vimMode is on)
+	help       help.Model
+	result     *ProjectSummary
+	quitting   bool
+}
+
+// ProjectPickerParams holds the parameters for the --projects selector
+type ProjectPickerParams struct {
+	Projects      []ProjectSummary // Projects to choose from, already sorted by frecency
+	InitialFilter string           // Pre-populated filter text
+	Config        *Config          // Optional: user preferences (defaults if nil)
+	Keys          *KeyMap          // Optional: key bindings (see KeyMapFromConfig; defaults if nil)
+}
+
+// ShowProjectPicker lets the user choose one of the known projects. Returns nil if
+// they quit. When stdout isn't a terminal, as in cd "$(alex-runner --projects)", the
+// picker is drawn on stderr so only the chosen path is captured.
+func ShowProjectPicker(params ProjectPickerParams) (*ProjectSummary, error) {
+	if len(params.Projects) == 0 {
+		return nil, fmt.Errorf("no projects available")
+	}
+
+	cfg := params.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	keys := DefaultKeyMap()
+	if params.Keys != nil {
+		keys = *params.Keys
+	}
+	keys = keys.pickerKeys()
+
+	output := os.Stdout
+	if !StdoutIsTerminal() {
+		output = os.Stderr
+		lipgloss.SetColorProfile(lipgloss.NewRenderer(os.Stderr).ColorProfile())
+	}
+	useTerminalBackground(output) // Before any styles are copied into the model
+
+	ti := textinput.New()
+	ti.Placeholder = "Type to filter..."
+	ti.Focus()
+	ti.CharLimit = filterCharLimit
+	ti.Prompt = "/ "
+	ti.SetValue(params.InitialFilter)
+
+	model := &projectPicker{
+		filter:     ti,
+		projects:   params.Projects,
+		inputKeys:  keys.inputKeys(cfg.VimMode),
+		normalKeys: keys.normalKeys(),
+		help:       newHelp(),
+	}
+	model.setTyping(!cfg.VimMode)
+	model.filterProjects()
+
+	model.inline, _ = ParseSelectorHeight(cfg.Height)
+
+	finalModel, err := tea.NewProgram(model, selectorProgramOptions(model, model.inline, output)...).Run()
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := finalModel.(*projectPicker); ok {
+		return m.result, nil
+	}
+	return nil, fmt.Errorf("no project selected")
+}
+
+// StdoutIsTerminal reports whether output goes to a terminal rather than a pipe or
+// command substitution
+func StdoutIsTerminal() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+func (m *projectPicker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles keyboard input and updates the model state
+func (m *projectPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		keys := m.activeKeys()
+		switch {
+		case key.Matches(msg, keys.Quit):
+			m.quitting = true
+			return m, tea.Quit
+
+		case key.Matches(msg, keys.Select):
+			if len(m.filtered) > 0 {
+				m.result = &m.filtered[m.selected]
+				m.quitting = true
+				return m, tea.Quit
+			}
+
+		case key.Matches(msg, keys.NormalMode):
+			// Vim mode: stop typing, keeping the filter
+			m.setTyping(false)
+
+		case key.Matches(msg, keys.InsertMode):
+			return m, m.setTyping(true)
+
+		case key.Matches(msg, keys.ClearFilter):
+			// Clear the filter, or quit if it's already empty
+			if m.filter.Value() == "" {
+				m.quitting = true
+				return m, tea.Quit
+			}
+			m.filter.SetValue("")
+			m.filterProjects()
+
+		case key.Matches(msg, keys.Up):
+			if m.selected > 0 {
+				m.selected--
+			} else {
+				m.selected = max(len(m.filtered)-1, 0) // Wrap to bottom
+			}
+			m.scrollToSelected()
+
+		case key.Matches(msg, keys.Down):
+			if m.selected < len(m.filtered)-1 {
+				m.selected++
+			} else {
+				m.selected = 0 // Wrap to top
+			}
+			m.scrollToSelected()
+
+		case m.typing:
+			m.filter, cmd = m.filter.Update(msg)
+			m.filterProjects()
+		}
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		if m.inline.Inline() {
+			m.height = m.inline.lines(msg.Height)
+		}
+		m.filter.Width = max(msg.Width-filterPromptWidth, 1)
+		m.scrollToSelected()
+	}
+
+	return m, cmd
+}
+
+// activeKeys returns the bindings of the current mode: typing into the filter, or
+// vim normal mode
+func (m *projectPicker) activeKeys() KeyMap {
+	if m.typing {
+		return m.inputKeys
+	}
+	return m.normalKeys
+}
+
+// setTyping switches between typing into the filter and vim normal mode
+func (m *projectPicker) setTyping(typing bool) tea.Cmd {
+	m.typing = typing
+	if typing {
+		return m.filter.Focus()
+	}
+	m.filter.Blur()
+	return nil
+}
+
+// filterProjects applies the filter text and moves the selection back to the top
+func (m *projectPicker) filterProjects() {
+	m.filtered = SearchProjects(m.projects, m.filter.Value())
+	m.selected = 0
+	m.offset = 0
+}
+
+// visibleRows is how many projects fit above the preview
+func (m *projectPicker) visibleRows() int {
+	if m.height == 0 {
+		return len(m.filtered)
+	}
+	return max(m.height-projectPickerFixedLines-m.previewRows(), 1)
+}
+
+// previewRows is how many scripts of the selected project are shown. A short inline
+// picker gives them up to keep minViewportHeight projects visible.
+func (m *projectPicker) previewRows() int {
+	if m.height == 0 {
+		return projectPreviewScripts
+	}
+	return min(projectPreviewScripts, max(m.height-projectPickerFixedLines-minViewportHeight, 0))
+}
+
+// scrollToSelected keeps the selected project within the visible rows
+func (m *projectPicker) scrollToSelected() {
+	rows := m.visibleRows()
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+rows {
+		m.offset = m.selected - rows + 1
+	}
+}
+
+// View renders the project list and the preview of the selected project
+func (m *projectPicker) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	var s strings.Builder
+	title := promptStyle.Render("📁 Projects (type to filter)")
+	if !m.typing {
+		title += metadataStyle.Render(" · normal mode")
+	}
+	s.WriteString(title + "\n\n")
+	s.WriteString(m.filter.View() + "\n\n")
+
+	if len(m.filtered) == 0 {
+		s.WriteString(metadataStyle.Render("No matching projects found") + "\n")
+	}
+
+	cursor := cursorStyle.Render("❯ ")
+	blank := strings.Repeat(" ", lipgloss.Width(cursor))
+	end := min(m.offset+m.visibleRows(), len(m.filtered))
+	for i := m.offset; i < end; i++ {
+		prefix := blank
+		if i == m.selected {
+			prefix = cursor
+		}
+		s.WriteString(prefix + formatProjectOption(m.filtered[i]) + "\n")
+	}
+
+	if len(m.filtered) > 0 {
+		selected := m.filtered[m.selected]
+		s.WriteString("\n" + previewLabelStyle.Render("Top scripts in "+ShortenHome(selected.Project.Directory)) + "\n")
+		line := lipgloss.NewStyle()
+		if m.width > 0 {
+			line = line.MaxWidth(m.width)
+		}
+		for _, scored := range selected.Scripts[:min(len(selected.Scripts), m.previewRows())] {
+			s.WriteString(line.Render("  "+scriptNameStyle.Render(scored.Script.Name)+metadataStyle.Render(" → ")+commandStyle.Render(scored.Script.Command)) + "\n")
+		}
+	}
+
+	m.help.Width = m.width
+	s.WriteString("\n" + m.help.View(m.activeKeys()))
+	return s.String()
+}
+
+// formatProjectOption renders "~/code/web [12 runs, 2h ago]"
+func formatProjectOption(summary ProjectSummary) string {
+	metadata := fmt.Sprintf("[%d runs", summary.UseCount)
+	if summary.LastUsed != nil {
+		metadata += ", " + FormatTimeAgo(*summary.LastUsed)
+	}
+	metadata += "]"
+	return scriptNameStyle.Render(ShortenHome(summary.Project.Directory)) + " " + metadataStyle.Render(metadata)
+}
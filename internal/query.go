@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Query filter keys. Other "key:value" words are plain text, since script names like
+// "test:unit" contain colons too.
+const (
+	queryKeySource    = "source"
+	queryKeyIs        = "is"
+	queryKeyWorkspace = "ws"
+	queryKeyTag       = "tag"
+)
+
+// Values of the "is:" filter
+const (
+	queryIsPinned = "pinned"
+	queryIsFailed = "failed"
+)
+
+// QueryFilter narrows results by script metadata, e.g. source:make or -is:pinned
+type QueryFilter struct {
+	Key     string
+	Value   string
+	Negated bool
+}
+
+// Query is a parsed search: metadata filters and excluded words are applied first,
+// then the remaining text is fuzzy ranked
+type Query struct {
+	Text     string        // Free text for fuzzy ranking, lowercased
+	Filters  []QueryFilter // Metadata filters, all of which must hold
+	Excluded []string      // Words (from -word) that must not appear in the name or command
+}
+
+// IsEmpty reports whether the query neither filters nor ranks
+func (q Query) IsEmpty() bool {
+	return q.Text == "" && len(q.Filters) == 0 && len(q.Excluded) == 0
+}
+
+// tokenizeQuery splits a query on whitespace. Double quotes group words, so
+// tag:"needs docker" is one token; the quotes themselves are dropped.
+func tokenizeQuery(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, started := false, false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// ParseQuery turns search input into a Query. Supported syntax:
+//
+//	source:make   only scripts from this source (make, npm, pnpm, yarn)
+//	is:pinned     only pinned scripts
+//	is:failed     only scripts whose last run failed
+//	ws:web        only scripts defined in a workspace whose path contains "web"
+//	tag:ci        only scripts tagged #ci in their description
+//	-e2e          exclude scripts with "e2e" in the name or command
+//	-source:make  any filter can be negated the same way
+func ParseQuery(query string) Query {
+	var q Query
+	var text []string
+
+	for _, token := range tokenizeQuery(strings.ToLower(query)) {
+		negated := false
+		word := token
+		if len(word) > 1 && word[0] == '-' && word[1] != '-' {
+			negated = true
+			word = word[1:]
+		}
+
+		if filter, ok := parseQueryFilter(word); ok {
+			filter.Negated = negated
+			q.Filters = append(q.Filters, filter)
+			continue
+		}
+		if negated {
+			q.Excluded = append(q.Excluded, word)
+			continue
+		}
+		text = append(text, token)
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q
+}
+
+// parseQueryFilter recognises "key:value" for the known filter keys
+func parseQueryFilter(word string) (QueryFilter, bool) {
+	key, value, ok := strings.Cut(word, ":")
+	if !ok || value == "" {
+		return QueryFilter{}, false
+	}
+
+	switch key {
+	case queryKeySource, queryKeyWorkspace, queryKeyTag:
+		return QueryFilter{Key: key, Value: value}, true
+	case queryKeyIs:
+		if value == queryIsPinned || value == queryIsFailed {
+			return QueryFilter{Key: key, Value: value}, true
+		}
+	}
+	return QueryFilter{}, false
+}
+
+// Matches reports whether a script passes the filters and excluded words
+func (q Query) Matches(scored ScoredScript) bool {
+	for _, filter := range q.Filters {
+		if filter.matches(scored) == filter.Negated {
+			return false
+		}
+	}
+
+	if len(q.Excluded) > 0 {
+		name := strings.ToLower(scored.Script.Name)
+		command := strings.ToLower(scored.Script.Command)
+		for _, word := range q.Excluded {
+			if strings.Contains(name, word) || strings.Contains(command, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matches evaluates the filter without its negation
+func (f QueryFilter) matches(scored ScoredScript) bool {
+	switch f.Key {
+	case queryKeySource:
+		return strings.ToLower(scored.Script.Source) == f.Value
+	case queryKeyIs:
+		if f.Value == queryIsPinned {
+			return scored.IsPinned
+		}
+		return scored.LastRunFailed
+	case queryKeyWorkspace:
+		workspace := ScriptWorkspace(scored.Script)
+		return workspace != "" && strings.Contains(strings.ToLower(workspace), f.Value)
+	case queryKeyTag:
+		for _, tag := range ScriptTags(scored.Script) {
+			if tag == f.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ScriptWorkspace returns the directory of the file defining a script, relative to
+// the project ("" for the project's own package.json or Makefile)
+func ScriptWorkspace(script NPMScript) string {
+	dir := path.Dir(strings.ReplaceAll(script.File, "\\", "/"))
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// ScriptTags returns the #hashtags in a script's description, lowercased and without
+// the #, e.g. "Run browser tests #ci #slow" has tags ci and slow
+func ScriptTags(script NPMScript) []string {
+	var tags []string
+	for _, word := range strings.Fields(script.Description) {
+		tag := strings.TrimRight(strings.TrimPrefix(word, "#"), ".,;:")
+		if strings.HasPrefix(word, "#") && tag != "" {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", nil},
+		{"  build  ", []string{"build"}},
+		{"source:make build", []string{"source:make", "build"}},
+		{`tag:"needs docker" -e2e`, []string{"tag:needs docker", "-e2e"}},
+		{`""`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizeQuery(tt.query); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("tokenizeQuery(%q) = %q, want %q", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected Query
+	}{
+		{"build docker", Query{Text: "build docker"}},
+		{"Source:Make build", Query{Text: "build", Filters: []QueryFilter{{Key: "source", Value: "make"}}}},
+		{"is:pinned", Query{Filters: []QueryFilter{{Key: "is", Value: "pinned"}}}},
+		{"-is:failed ws:web test", Query{Text: "test", Filters: []QueryFilter{
+			{Key: "is", Value: "failed", Negated: true},
+			{Key: "ws", Value: "web"},
+		}}},
+		{"-e2e test", Query{Text: "test", Excluded: []string{"e2e"}}},
+		// Script names with colons, unknown keys and flags stay text
+		{"test:unit", Query{Text: "test:unit"}},
+		{"is:slow source:", Query{Text: "is:slow source:"}},
+		{"--watch -", Query{Text: "--watch -"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseQuery(tt.query); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestSearchScriptsWithFilters(t *testing.T) {
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "build", Command: "go build", Source: "make"}, FrecencyScore: 5},
+		{Script: NPMScript{Name: "build", Command: "vite build", Source: "pnpm"}, FrecencyScore: 4, IsPinned: true},
+		{Script: NPMScript{Name: "test", Command: "vitest", Source: "pnpm", Description: "Unit tests #ci"}, FrecencyScore: 3, LastRunFailed: true},
+		{Script: NPMScript{Name: "test:e2e", Command: "playwright test", Source: "pnpm", Description: "Browser tests #ci #slow"}, FrecencyScore: 2},
+		{Script: NPMScript{Name: "dev", Command: "vite", Source: "pnpm", File: "apps/web/package.json"}, FrecencyScore: 1},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string // name:source in order
+	}{
+		{"source:make build", []string{"build:make"}},
+		{"-source:make build", []string{"build:pnpm"}},
+		{"is:pinned", []string{"build:pnpm"}},
+		{"is:failed", []string{"test:pnpm"}},
+		{"tag:ci", []string{"test:pnpm", "test:e2e:pnpm"}},
+		{"tag:ci -tag:slow", []string{"test:pnpm"}},
+		{"-e2e test", []string{"test:pnpm"}},
+		{"ws:web", []string{"dev:pnpm"}},
+		{"ws:web build", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, scored := range SearchScripts(scripts, tt.query) {
+			got = append(got, scriptKey(scored))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SearchScripts(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestRankerMarksFailedScripts(t *testing.T) {
+	scripts := []NPMScript{{Name: "test", Source: "npm"}, {Name: "lint", Source: "npm"}}
+	usage := []ScriptUsage{
+		{ScriptName: "test", Source: "npm", UseCount: 2, LastUsed: testNow},
+		{ScriptName: "lint", Source: "npm", UseCount: 2, LastUsed: testNow},
+	}
+	runs := []ScriptRun{
+		{ScriptName: "test", Source: "npm", ExitCode: 1, StartedAt: testNow},
+		{ScriptName: "lint", Source: "npm", ExitCode: 0, StartedAt: testNow},
+		{ScriptName: "lint", Source: "npm", ExitCode: 2, StartedAt: testNow.Add(-time.Hour)},
+	}
+
+	for _, scored := range (Ranker{Clock: fixedClock(testNow), Runs: runs}).ScoreScripts(scripts, usage) {
+		if scored.LastRunFailed != (scored.Script.Name == "test") {
+			t.Errorf("%s: LastRunFailed = %v", scored.Script.Name, scored.LastRunFailed)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const declinedRenameKeyPrefix = "declined_rename:"
+
+// ScriptRename is a script with history that disappeared while a new script with the
+// same command appeared, so it was most likely renamed
+type ScriptRename struct {
+	Old     ScriptUsage // History of the old name
+	New     NPMScript   // Current script with the same command
+	Command string      // Command both share
+}
+
+// String describes the rename, e.g. "test:unit → unit-test"
+func (r ScriptRename) String() string {
+	oldName, newName := r.Old.ScriptName, r.New.Name
+	if r.Old.Source != r.New.Source {
+		oldName = fmt.Sprintf("%s (%s)", oldName, r.Old.Source)
+		newName = fmt.Sprintf("%s (%s)", newName, r.New.Source)
+	}
+	return oldName + " → " + newName
+}
+
+// normalizeCommand makes commands comparable regardless of spacing
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// DetectRenames compares current scripts against usage history. A script with history
+// but no current definition is matched to a script without history when the command
+// last recorded for the old name equals the new script's command. Ambiguous matches
+// and renames the user declined are skipped.
+func (d *Database) DetectRenames(directory string, scripts []NPMScript) ([]ScriptRename, error) {
+	usages, err := d.GetUsageStats(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool, len(scripts))
+	for _, script := range scripts {
+		current[script.Name+":"+script.Source] = true
+	}
+	withHistory := make(map[string]bool, len(usages))
+	for _, usage := range usages {
+		withHistory[usage.ScriptName+":"+usage.Source] = true
+	}
+
+	// New scripts by command; a command shared by several new scripts is ambiguous
+	newByCommand := make(map[string][]NPMScript)
+	for _, script := range scripts {
+		if withHistory[script.Name+":"+script.Source] {
+			continue
+		}
+		command := normalizeCommand(script.Command)
+		if command != "" {
+			newByCommand[command] = append(newByCommand[command], script)
+		}
+	}
+	if len(newByCommand) == 0 {
+		return nil, nil
+	}
+
+	var candidates []ScriptRename
+	claimed := make(map[string]int) // New script key -> number of old scripts matching it
+	for _, usage := range usages {
+		if current[usage.ScriptName+":"+usage.Source] {
+			continue
+		}
+
+		runs, err := d.GetRecentRuns(directory, usage.ScriptName, usage.Source, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 {
+			continue // No command recorded to compare against
+		}
+
+		command := normalizeCommand(runs[0].Command)
+		matches := newByCommand[command]
+		if len(matches) != 1 {
+			continue
+		}
+
+		candidates = append(candidates, ScriptRename{Old: usage, New: matches[0], Command: command})
+		claimed[matches[0].Name+":"+matches[0].Source]++
+	}
+
+	var renames []ScriptRename
+	for _, rename := range candidates {
+		if claimed[rename.New.Name+":"+rename.New.Source] > 1 {
+			continue
+		}
+		declined, err := d.renameDeclined(directory, rename)
+		if err != nil {
+			return nil, err
+		}
+		if !declined {
+			renames = append(renames, rename)
+		}
+	}
+	return renames, nil
+}
+
+// MigrateRename carries use count, last used time, pin, run history and remembered
+// Makefile variables over from the old name to the new one
+func (d *Database) MigrateRename(directory string, rename ScriptRename) error {
+	return d.withTx(func(tx *sql.Tx) error {
+		usage := ExportedUsage{
+			Directory:  directory,
+			ScriptName: rename.New.Name,
+			Source:     rename.New.Source,
+			LastUsed:   rename.Old.LastUsed,
+			UseCount:   rename.Old.UseCount,
+			IsPinned:   rename.Old.IsPinned,
+		}
+		if err := importUsage(tx, usage, MergeSum); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`DELETE FROM script_usage WHERE directory = ? AND script_name = ? AND source = ?`,
+			directory, rename.Old.ScriptName, rename.Old.Source)
+		if err != nil {
+			return fmt.Errorf("failed to remove old usage of %s: %w", rename.Old.ScriptName, err)
+		}
+
+		_, err = tx.Exec(`UPDATE script_runs SET script_name = ?, source = ? WHERE directory = ? AND script_name = ? AND source = ?`,
+			rename.New.Name, rename.New.Source, directory, rename.Old.ScriptName, rename.Old.Source)
+		if err != nil {
+			return fmt.Errorf("failed to move run history of %s: %w", rename.Old.ScriptName, err)
+		}
+
+		if rename.Old.Source == "make" && rename.New.Source == "make" {
+			_, err = tx.Exec(`UPDATE OR IGNORE make_variables SET target = ? WHERE directory = ? AND target = ?`,
+				rename.New.Name, directory, rename.Old.ScriptName)
+			if err != nil {
+				return fmt.Errorf("failed to move variables of %s: %w", rename.Old.ScriptName, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeclineRename remembers that a detected rename is wrong so it isn't offered again
+func (d *Database) DeclineRename(directory string, rename ScriptRename) error {
+	_, err := d.db.Exec(`INSERT INTO metadata (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		declinedRenameKey(directory, rename), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to remember declined rename: %w", err)
+	}
+	return nil
+}
+
+// renameDeclined reports whether the user said no to this rename before
+func (d *Database) renameDeclined(directory string, rename ScriptRename) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM metadata WHERE key = ?`, declinedRenameKey(directory, rename)).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check declined renames: %w", err)
+	}
+	return count > 0, nil
+}
+
+// declinedRenameKey identifies a rename in the metadata table
+func declinedRenameKey(directory string, rename ScriptRename) string {
+	return strings.Join([]string{
+		declinedRenameKeyPrefix + directory,
+		rename.Old.ScriptName + ":" + rename.Old.Source,
+		rename.New.Name + ":" + rename.New.Source,
+	}, "\x00")
+}
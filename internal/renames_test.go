@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+// recordScriptRun records usage and a run, the way running a script from the selector does
+func recordScriptRun(t *testing.T, db *Database, directory string, script NPMScript) {
+	t.Helper()
+	if err := db.RecordUsage(directory, script.Name, script.Source); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+	err := db.RecordRun(ScriptRun{
+		Directory:  directory,
+		ScriptName: script.Name,
+		Source:     script.Source,
+		Command:    script.Command,
+		StartedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		recordScriptRun(t, db, "/test", NPMScript{Name: "test:unit", Command: "vitest  run", Source: "pnpm"})
+	}
+	recordScriptRun(t, db, "/test", NPMScript{Name: "dev", Command: "vite", Source: "pnpm"})
+	db.RecordUsage("/test", "lint", "pnpm") // Removed, but no command recorded to match on
+
+	scripts := []NPMScript{
+		{Name: "unit-test", Command: "vitest run", Source: "pnpm"},
+		{Name: "dev", Command: "vite", Source: "pnpm"},
+		{Name: "check", Command: "eslint .", Source: "pnpm"},
+	}
+
+	renames, err := db.DetectRenames("/test", scripts)
+	if err != nil {
+		t.Fatalf("DetectRenames() error = %v", err)
+	}
+	if len(renames) != 1 {
+		t.Fatalf("expected 1 rename, got %+v", renames)
+	}
+	if renames[0].Old.ScriptName != "test:unit" || renames[0].New.Name != "unit-test" {
+		t.Errorf("unexpected rename %s", renames[0])
+	}
+	if renames[0].String() != "test:unit → unit-test" {
+		t.Errorf("unexpected description %q", renames[0].String())
+	}
+}
+
+func TestDetectRenamesSkipsAmbiguousMatches(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	recordScriptRun(t, db, "/test", NPMScript{Name: "build", Command: "tsc", Source: "npm"})
+
+	// Two new scripts with the same command: no way to tell which one is the rename
+	scripts := []NPMScript{
+		{Name: "build:types", Command: "tsc", Source: "npm"},
+		{Name: "typecheck", Command: "tsc", Source: "npm"},
+	}
+	renames, _ := db.DetectRenames("/test", scripts)
+	if len(renames) != 0 {
+		t.Errorf("expected ambiguous match to be skipped, got %+v", renames)
+	}
+}
+
+func TestMigrateRename(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	old := NPMScript{Name: "ship", Command: "./deploy.sh", Source: "make"}
+	recordScriptRun(t, db, "/test", old)
+	recordScriptRun(t, db, "/test", old)
+	db.PinScript("/test", "ship", "make")
+	db.SaveMakeVariables("/test", "ship", map[string]string{"ENV": "prod"})
+
+	scripts := []NPMScript{{Name: "deploy", Command: "./deploy.sh", Source: "make"}}
+	renames, _ := db.DetectRenames("/test", scripts)
+	if len(renames) != 1 {
+		t.Fatalf("expected 1 rename, got %+v", renames)
+	}
+
+	if err := db.MigrateRename("/test", renames[0]); err != nil {
+		t.Fatalf("MigrateRename() error = %v", err)
+	}
+
+	stats, _ := db.GetUsageStats("/test")
+	if len(stats) != 1 || stats[0].ScriptName != "deploy" || stats[0].UseCount != 2 || !stats[0].IsPinned {
+		t.Errorf("expected pinned deploy with 2 uses, got %+v", stats)
+	}
+	if runs, _ := db.GetRecentRuns("/test", "deploy", "make", 10); len(runs) != 2 {
+		t.Errorf("expected run history to move, got %d runs", len(runs))
+	}
+	if variables, _ := db.GetMakeVariables("/test", "deploy"); variables["ENV"] != "prod" {
+		t.Errorf("expected make variables to move, got %v", variables)
+	}
+
+	if renames, _ := db.DetectRenames("/test", scripts); len(renames) != 0 {
+		t.Errorf("expected nothing left to migrate, got %+v", renames)
+	}
+}
+
+func TestDeclinedRenameIsNotOfferedAgain(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	recordScriptRun(t, db, "/test", NPMScript{Name: "start", Command: "node server.js", Source: "npm"})
+	scripts := []NPMScript{{Name: "serve", Command: "node server.js", Source: "npm"}}
+
+	renames, _ := db.DetectRenames("/test", scripts)
+	if len(renames) != 1 {
+		t.Fatalf("expected 1 rename, got %+v", renames)
+	}
+	if err := db.DeclineRename("/test", renames[0]); err != nil {
+		t.Fatalf("DeclineRename() error = %v", err)
+	}
+
+	if renames, _ := db.DetectRenames("/test", scripts); len(renames) != 0 {
+		t.Errorf("expected declined rename to be skipped, got %+v", renames)
+	}
+}
@@ -1,5 +1,13 @@
 package runner
 
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
 // BuildScriptArgs constructs the command arguments for executing a script
 // based on the package manager or build tool being used.
 //
@@ -8,6 +16,7 @@ package runner
 //   - pnpm and yarn pass args through automatically
 //
 // For make:
+//   - variable overrides are passed as VAR=value, sorted by name
 //   - args are appended directly
 func BuildScriptArgs(params BuildScriptArgsParams) []string {
 	var cmdArgs []string
@@ -23,8 +32,18 @@ func BuildScriptArgs(params BuildScriptArgsParams) []string {
 			cmdArgs = append(cmdArgs, params.AdditionalArgs...)
 		}
 	} else {
-		// For make, just append args directly
+		// For make, pass variables as VAR=value and append args directly
 		cmdArgs = []string{params.ScriptName}
+
+		names := make([]string, 0, len(params.Variables))
+		for name := range params.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cmdArgs = append(cmdArgs, name+"="+params.Variables[name])
+		}
+
 		cmdArgs = append(cmdArgs, params.AdditionalArgs...)
 	}
 
@@ -32,10 +51,12 @@ func BuildScriptArgs(params BuildScriptArgsParams) []string {
 }
 
 type BuildScriptArgsParams struct {
-	Command        string   // "npm", "pnpm", "yarn", "make"
-	ScriptName     string   // The script/target to run
-	UseRun         bool     // Whether to use "run" subcommand (for npm/pnpm/yarn)
-	AdditionalArgs []string // Additional arguments to pass to the script
+	Command        string            // "npm", "pnpm", "yarn", "make"
+	ScriptName     string            // The script/target to run
+	UseRun         bool              // Whether to use "run" subcommand (for npm/pnpm/yarn)
+	AdditionalArgs []string          // Additional arguments to pass to the script
+	Variables      map[string]string // Make variable overrides (ignored for npm/pnpm/yarn)
+	Dir            string            // Directory to run in (the current directory if empty)
 }
 
 // ParseArgs separates arguments at the '--' separator
@@ -48,3 +69,87 @@ func ParseArgs(args []string) (beforeSep []string, afterSep []string) {
 	}
 	return args, nil
 }
+
+// JoinArgs joins arguments into a single shell-style string, quoting any
+// argument that contains whitespace or quotes so it can be read back unambiguously
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`") {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SplitArgs splits a shell-style argument string into arguments, honouring
+// single quotes, double quotes and backslash escapes (the inverse of JoinArgs)
+func SplitArgs(input string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+				i++
+				current.WriteRune(runes[i])
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			}
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ExitCodeFromError returns the process exit code for an error returned by exec.Cmd.Run
+// Returns 0 for a nil error and -1 if the process never ran or was killed by a signal
+func ExitCodeFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
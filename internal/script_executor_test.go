@@ -1,6 +1,8 @@
 package runner
 
 import (
+	"errors"
+	"os/exec"
 	"reflect"
 	"testing"
 )
@@ -231,3 +233,120 @@ func TestBuildScriptArgs_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"--watch"}, "--watch"},
+		{[]string{"--grep", "login flow"}, "--grep 'login flow'"},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{""}, "''"},
+	}
+
+	for _, tt := range tests {
+		if got := JoinArgs(tt.args); got != tt.expected {
+			t.Errorf("JoinArgs(%q) = %q, want %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestExitCodeFromError(t *testing.T) {
+	if code := ExitCodeFromError(nil); code != 0 {
+		t.Errorf("ExitCodeFromError(nil) = %d, want 0", code)
+	}
+
+	if code := ExitCodeFromError(errors.New("exec: not found")); code != -1 {
+		t.Errorf("ExitCodeFromError(non-exit error) = %d, want -1", code)
+	}
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	if code := ExitCodeFromError(err); code != 3 {
+		t.Errorf("ExitCodeFromError(exit 3) = %d, want 3", code)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"--watch", []string{"--watch"}},
+		{"  src/app.test.ts   --watch ", []string{"src/app.test.ts", "--watch"}},
+		{`--grep 'login flow'`, []string{"--grep", "login flow"}},
+		{`--grep "login flow"`, []string{"--grep", "login flow"}},
+		{`a\ b`, []string{"a b"}},
+		{`''`, []string{""}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.input)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) error = %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSplitArgsUnterminatedQuote(t *testing.T) {
+	if _, err := SplitArgs(`--grep 'login`); err == nil {
+		t.Error("expected an error for an unterminated quote")
+	}
+}
+
+func TestSplitArgsRoundTrip(t *testing.T) {
+	args := []string{"--name", "it's here", "", `C:\path`, "$HOME"}
+	got, err := SplitArgs(JoinArgs(args))
+	if err != nil {
+		t.Fatalf("SplitArgs() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, args) {
+		t.Errorf("SplitArgs(JoinArgs(%q)) = %q", args, got)
+	}
+}
+
+func TestBuildScriptArgs_MakeVariables(t *testing.T) {
+	result := BuildScriptArgs(BuildScriptArgsParams{
+		Command:        "make",
+		ScriptName:     "deploy",
+		UseRun:         false,
+		AdditionalArgs: []string{"--dry-run"},
+		Variables:      map[string]string{"TAG": "v1.2.0", "ENV": "staging"},
+	})
+
+	expected := []string{"deploy", "ENV=staging", "TAG=v1.2.0", "--dry-run"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestBuildScriptArgs_VariablesIgnoredForRun(t *testing.T) {
+	result := BuildScriptArgs(BuildScriptArgsParams{
+		Command:    "pnpm",
+		ScriptName: "build",
+		UseRun:     true,
+		Variables:  map[string]string{"ENV": "staging"},
+	})
+
+	expected := []string{"run", "build"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestMakeOverrides(t *testing.T) {
+	variables := []MakeVariable{{Name: "ENV", Default: "dev"}, {Name: "TAG"}}
+	values := map[string]string{"ENV": "staging", "TAG": "", "REMOVED": "x"}
+
+	expected := map[string]string{"ENV": "staging"}
+	if got := MakeOverrides(variables, values); !reflect.DeepEqual(got, expected) {
+		t.Errorf("MakeOverrides() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ScriptSources selects which files LoadScripts reads
+type ScriptSources struct {
+	Makefile       bool   // Read Makefile targets, if there is a Makefile
+	PackageJSON    bool   // Read package.json scripts, if there is a package.json
+	PackageManager string // Source recorded for package.json scripts ("npm", "pnpm", "yarn")
+	Workspaces     bool   // Also read the scripts of workspace packages (see LoadWorkspaceScripts)
+}
+
+// LoadScripts reads the runnable scripts of a directory: Makefile targets with a
+// recipe, then package.json scripts, and those of workspace packages when
+// sources.Workspaces is set. Also returns every Makefile target, including
+// aggregates without a recipe, for the dependency graph.
+func LoadScripts(directory string, sources ScriptSources) ([]NPMScript, []MakeTarget, error) {
+	var scripts []NPMScript
+	var allTargets []MakeTarget
+
+	if sources.Makefile && MakefileExists(directory) {
+		targets, err := ReadAllMakefileTargets(directory)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Makefile: %w", err)
+		}
+		allTargets = targets
+		for _, target := range targets {
+			if target.Command == "" {
+				continue
+			}
+			scripts = append(scripts, NPMScript{
+				Name:        target.Name,
+				Command:     target.Command,
+				Source:      "make",
+				Description: target.Description,
+				File:        "Makefile",
+				Line:        target.Line,
+				Variables:   target.Variables,
+			})
+		}
+	}
+
+	if sources.PackageJSON && PackageJSONExists(directory) {
+		// A monorepo root often has no scripts of its own, only its workspaces do
+		var workspaceScripts []NPMScript
+		if sources.Workspaces {
+			workspaceScripts = LoadWorkspaceScripts(directory)
+		}
+		pkg, err := ReadPackageJSON(directory)
+		if err != nil && !(errors.Is(err, ErrNoScripts) && len(workspaceScripts) > 0) {
+			return nil, nil, err
+		}
+		var pkgScripts []NPMScript
+		if pkg != nil {
+			pkgScripts = GetScripts(pkg)
+		}
+		pkgScripts = append(pkgScripts, workspaceScripts...)
+		for i := range pkgScripts {
+			pkgScripts[i].Source = sources.PackageManager
+		}
+		scripts = append(scripts, pkgScripts...)
+	}
+
+	return scripts, allTargets, nil
+}
@@ -1,213 +1,361 @@
 package runner
 
 import (
+	"math"
+	"path/filepath"
 	"sort"
 	"strings"
-
-	"github.com/lithammer/fuzzysearch/fuzzy"
-	"github.com/schollz/closestmatch"
+	"unicode"
 )
 
-// Search Configuration Constants
+// Search scoring, modelled on fzf: every matched character scores, gaps between
+// matched characters cost, and characters at word starts score extra.
 const (
-	// Minimum rank threshold for multi-word search results
-	// This filters out weak matches when searching with multiple terms
-	// For example, "hello docker" should not match scripts with only "hello"
-	// Increase this value to be more strict, decrease to be more lenient
-	multiWordMinRank = 980 // Scripts must rank at least 980 to be included (top 2-3 from combined matcher)
-
-	// Maximum number of results to request from closestmatch
-	// This prevents weak matches from getting high ranks in small script lists
-	// For example, if you have 10 scripts and search for "hello docker",
-	// we don't want all 10 scripts to get ranks 1000-910
-	maxClosestMatchResults = 3 // Only consider top 3 matches from each matcher
+	scoreMatch        = 16 // Each matched character
+	scoreGapStart     = -3 // First skipped character between two matches
+	scoreGapExtension = -1 // Every further skipped character
+
+	bonusBoundary            = scoreMatch / 2                       // Match at the start of the text or after - _ : / . or a space
+	bonusCamelCase           = bonusBoundary - 1                    // Match at a lower→upper or letter→digit change
+	bonusConsecutive         = -(scoreGapStart + scoreGapExtension) // Match right after the previous one
+	bonusFirstCharMultiplier = 2                                    // The first query character's bonus counts double
+	bonusExactName           = 2 * scoreMatch                       // The token is the whole script name
+
+	commandMatchWeight = 0.5 // A token found only in the command scores half as much
+	frecencyBlend      = 8.0 // Score per e-fold of frecency, so frecency breaks near-ties in match quality
+
+	typoMinWordLength = 3 // Shorter words must match as typed
+	typoCharsPerEdit  = 4 // One typo allowed per this many characters (at least one)
 )
 
 type searchResult struct {
 	scored ScoredScript
-	rank   int
+	score  float64
 }
 
+// SearchScripts filters scripts by the query's metadata filters and excluded words
+// (see ParseQuery), then ranks the rest by how well they match its text
 func SearchScripts(scoredScripts []ScoredScript, query string) []ScoredScript {
-	query = strings.ToLower(strings.TrimSpace(query))
-	if query == "" {
+	parsed := ParseQuery(query)
+	if parsed.IsEmpty() {
 		return scoredScripts
 	}
 
-	// Split query into words to detect multi-word queries
-	queryWords := strings.Fields(query)
-	isMultiWord := len(queryWords) > 1
+	if len(parsed.Filters) > 0 || len(parsed.Excluded) > 0 {
+		var matching []ScoredScript
+		for _, scored := range scoredScripts {
+			if parsed.Matches(scored) {
+				matching = append(matching, scored)
+			}
+		}
+		if parsed.Text == "" {
+			return matching // Filters only: keep the frecency order
+		}
+		scoredScripts = matching
+	}
+
+	return rankScripts(scoredScripts, parsed.Text)
+}
 
-	// For multi-word queries, use closestmatch for better results
-	if isMultiWord {
-		return searchWithClosestMatch(scoredScripts, query)
+// rankScripts keeps the scripts in which every word of the query matches the name
+// or the command, ordered by match quality blended with frecency. Each word is
+// matched fuzzily, so "bdk" finds "build:docker" and "docker build" finds
+// "start-docker:traefik:build".
+func rankScripts(scoredScripts []ScoredScript, query string) []ScoredScript {
+	tokens := strings.Fields(query)
+	if len(tokens) == 0 {
+		return scoredScripts
 	}
 
-	// Single word query - use existing fuzzy logic
 	var results []searchResult
-
 	for _, scored := range scoredScripts {
-		scriptName := strings.ToLower(scored.Script.Name)
-		scriptCommand := strings.ToLower(scored.Script.Command)
-
-		rank := 0
-
-		// Exact match on name gets highest priority
-		if scriptName == query {
-			rank = 1000
-		} else if strings.HasPrefix(scriptName, query) {
-			rank = 500
-		} else if strings.Contains(scriptName, query) {
-			rank = 300
-		} else if fuzzy.Match(query, scriptName) {
-			// Fuzzy match on name
-			rank = 200
-		} else if strings.Contains(scriptCommand, query) {
-			// Match in command gets lower priority
-			rank = 100
-		} else if fuzzy.Match(query, scriptCommand) {
-			// Fuzzy match in command
-			rank = 50
-		}
-
-		if rank > 0 {
-			results = append(results, searchResult{
-				scored: scored,
-				rank:   rank,
-			})
-		}
-	}
-
-	// Sort by rank descending, then by frecency score descending
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].rank != results[j].rank {
-			return results[i].rank > results[j].rank
+		score, nameMatches, commandMatches, ok := scoreScript(scored, tokens)
+		if !ok {
+			continue
+		}
+
+		scored.NameMatches, scored.CommandMatches = nameMatches, commandMatches
+		results = append(results, searchResult{
+			scored: scored,
+			score:  score + frecencyBlend*math.Log1p(math.Max(scored.FrecencyScore, 0)),
+		})
+	}
+
+	// Last resort: tolerate typos like "tset" for "test"
+	if len(results) == 0 {
+		return typoMatches(scoredScripts, tokens)
+	}
+
+	// Sort by score descending, then by frecency score descending
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
 		}
 		return results[i].scored.FrecencyScore > results[j].scored.FrecencyScore
 	})
 
-	// Extract sorted scripts
-	var searchedScripts []ScoredScript
+	searchedScripts := make([]ScoredScript, 0, len(results))
 	for _, result := range results {
 		searchedScripts = append(searchedScripts, result.scored)
 	}
-
 	return searchedScripts
 }
 
-// searchWithClosestMatch uses bag-of-words matching for multi-word queries
-// This handles queries like "build docker" or "docker build" effectively
-func searchWithClosestMatch(scoredScripts []ScoredScript, query string) []ScoredScript {
-	if len(scoredScripts) == 0 {
-		return scoredScripts
+// scoreScript matches every token against the script's name, or failing that its
+// command, or in --global mode its project's folder name, and sums the scores. ok is
+// false if any token matches none of them.
+func scoreScript(scored ScoredScript, tokens []string) (score float64, nameMatches, commandMatches []int, ok bool) {
+	script := scored.Script
+	name := []rune(script.Name)
+	command := []rune(script.Command)
+	var project []rune
+	if scored.Project != nil {
+		project = []rune(filepath.Base(scored.Project.Directory))
 	}
 
-	// Build lists of script names and commands for matching
-	scriptNames := make([]string, len(scoredScripts))
-	scriptCommands := make([]string, len(scoredScripts))
-	scriptCombined := make([]string, len(scoredScripts))
+	for _, token := range tokens {
+		pattern := []rune(token)
 
-	for i, scored := range scoredScripts {
-		scriptNames[i] = strings.ToLower(scored.Script.Name)
-		scriptCommands[i] = strings.ToLower(scored.Script.Command)
-		scriptCombined[i] = scriptNames[i] + " " + scriptCommands[i]
+		nameScore, namePositions := fuzzyScore(name, pattern)
+		if namePositions != nil && strings.EqualFold(script.Name, token) {
+			nameScore += bonusExactName
+		}
+		commandScore, commandPositions := fuzzyScore(command, pattern)
+
+		switch {
+		case namePositions != nil && (commandPositions == nil || float64(nameScore) >= float64(commandScore)*commandMatchWeight):
+			score += float64(nameScore)
+			nameMatches = append(nameMatches, namePositions...)
+		case commandPositions != nil:
+			score += float64(commandScore) * commandMatchWeight
+			commandMatches = append(commandMatches, commandPositions...)
+		default:
+			projectScore, projectPositions := fuzzyScore(project, pattern)
+			if projectPositions == nil {
+				return 0, nil, nil, false
+			}
+			score += float64(projectScore) * commandMatchWeight
+		}
 	}
 
-	// Create closestmatch instances with n-gram sizes optimized for script names
-	cmNames := closestmatch.New(scriptNames, []int{2, 3, 4})
-	cmCommands := closestmatch.New(scriptCommands, []int{2, 3, 4})
-	cmCombined := closestmatch.New(scriptCombined, []int{2, 3, 4})
+	return score, uniqueSorted(nameMatches), uniqueSorted(commandMatches), true
+}
 
-	// Get matches from each matcher - limit to top N to avoid weak matches getting high ranks
-	maxResults := min(maxClosestMatchResults, len(scoredScripts))
-	nameMatches := cmNames.ClosestN(query, maxResults)
-	commandMatches := cmCommands.ClosestN(query, maxResults)
-	combinedMatches := cmCombined.ClosestN(query, maxResults)
+// fuzzyScore finds the best placement of pattern's characters, in order and case
+// insensitively, in text. Returns the score and the rune indexes of the matched
+// characters, or nil positions if pattern is not a subsequence of text.
+func fuzzyScore(text, pattern []rune) (int, []int) {
+	n, m := len(text), len(pattern)
+	if m == 0 || m > n {
+		return 0, nil
+	}
 
-	// Build a rank map: script -> rank
-	rankMap := make(map[string]int)
+	lower := make([]rune, n)
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+	for i, r := range pattern {
+		pattern[i] = unicode.ToLower(r)
+	}
 
-	// Rank based on position in match lists (earlier = better)
-	// Combined matches get highest weight, then name, then command
-	for i, match := range combinedMatches {
-		if match != "" {
-			// Extract name from combined (before the space)
-			name := strings.SplitN(match, " ", 2)[0]
-			if _, exists := rankMap[name]; !exists {
-				rankMap[name] = 1000 - (i * 10) // Top match gets 1000, decreasing by 10
-			}
+	// best[k][j]: best score with pattern[k] matched at text[j] (minInt if impossible)
+	// from[k][j]: where pattern[k-1] was matched in that placement
+	const minInt = math.MinInt / 2
+	best := make([][]int, m)
+	from := make([][]int, m)
+	for k := range best {
+		best[k] = make([]int, n)
+		from[k] = make([]int, n)
+		for j := range best[k] {
+			best[k][j] = minInt
 		}
 	}
 
-	for i, match := range nameMatches {
-		if match != "" {
-			if _, exists := rankMap[match]; !exists {
-				rankMap[match] = 800 - (i * 10)
-			}
+	for j := 0; j < n; j++ {
+		if lower[j] == pattern[0] {
+			best[0][j] = scoreMatch + boundaryBonus(text, j)*bonusFirstCharMultiplier
 		}
 	}
 
-	for i, cmdMatch := range commandMatches {
-		if cmdMatch != "" {
-			// Find script by command
-			for j, cmd := range scriptCommands {
-				if cmd == cmdMatch {
-					name := scriptNames[j]
-					if _, exists := rankMap[name]; !exists {
-						rankMap[name] = 600 - (i * 10)
-					}
-					break
+	for k := 1; k < m; k++ {
+		// Running best of best[k-1][i] - (gap penalty growing with j) over i < j-1
+		gapBest, gapFrom := minInt, -1
+		for j := k; j < n; j++ {
+			if i := j - 2; i >= 0 && best[k-1][i] > minInt {
+				// Skipping text[i+1..j-1] costs scoreGapStart + (j-i-2)*scoreGapExtension;
+				// store the part independent of j, the rest is added below
+				if candidate := best[k-1][i] + scoreGapStart - (i+1)*scoreGapExtension; candidate > gapBest {
+					gapBest, gapFrom = candidate, i
+				}
+			}
+			if lower[j] != pattern[k] {
+				continue
+			}
+
+			bonus := boundaryBonus(text, j)
+			if prev := best[k-1][j-1]; prev > minInt {
+				best[k][j] = prev + scoreMatch + max(bonus, bonusConsecutive)
+				from[k][j] = j - 1
+			}
+			if gapFrom >= 0 {
+				if candidate := gapBest + (j-1)*scoreGapExtension + scoreMatch + bonus; candidate > best[k][j] {
+					best[k][j] = candidate
+					from[k][j] = gapFrom
 				}
 			}
 		}
 	}
 
-	// Build results with ranks, filtering by minimum threshold
-	// Also check that results contain tokens from multiple query words
-	queryWords := strings.Fields(strings.ToLower(query))
-	var results []searchResult
+	end, score := -1, minInt
+	for j := m - 1; j < n; j++ {
+		if best[m-1][j] > score {
+			end, score = j, best[m-1][j]
+		}
+	}
+	if end < 0 {
+		return 0, nil
+	}
+
+	positions := make([]int, m)
+	for k, j := m-1, end; k >= 0; k-- {
+		positions[k] = j
+		j = from[k][j]
+	}
+	return score, positions
+}
+
+// boundaryBonus rewards matching at the start of a word in text
+func boundaryBonus(text []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, cur := text[i-1], text[i]
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur),
+		unicode.IsLetter(prev) && unicode.IsDigit(cur):
+		return bonusCamelCase
+	}
+	return 0
+}
+
+// uniqueSorted sorts positions and drops duplicates from overlapping tokens
+func uniqueSorted(positions []int) []int {
+	if len(positions) == 0 {
+		return nil
+	}
+	sort.Ints(positions)
+	unique := positions[:1]
+	for _, pos := range positions[1:] {
+		if pos != unique[len(unique)-1] {
+			unique = append(unique, pos)
+		}
+	}
+	return unique
+}
+
+// typoMatches finds scripts whose name is within a few typos of the query: every
+// word has to be close to the whole name or one of its parts ("db:migrate" has db
+// and migrate). Ordered by number of typos, then by how much of the name the words
+// cover, then frecency.
+func typoMatches(scoredScripts []ScoredScript, tokens []string) []ScoredScript {
+	type typoResult struct {
+		scored ScoredScript
+		edits  int
+		extra  int // Name parts no word was matched against
+	}
+
+	var results []typoResult
 	for _, scored := range scoredScripts {
 		name := strings.ToLower(scored.Script.Name)
-		command := strings.ToLower(scored.Script.Command)
-		combined := name + " " + command
-
-		if rank, hasRank := rankMap[name]; hasRank {
-			if rank >= multiWordMinRank {
-				// Additional filter: for multi-word queries, check that the result
-				// contains substrings matching multiple query words
-				matchCount := 0
-				for _, qWord := range queryWords {
-					if strings.Contains(combined, qWord) {
-						matchCount++
-					}
-				}
-
-				// Only include if it matches at least 2 query words (for 2+ word queries)
-				if len(queryWords) >= 2 && matchCount < 2 {
+		parts := strings.FieldsFunc(name, isNameSeparator)
+		candidates := append(parts, name)
+
+		total, matched := 0, 0
+		for _, token := range tokens {
+			edits, best := -1, ""
+			for _, candidate := range candidates {
+				distance := editDistance(token, candidate)
+				if distance > maxTypos(token) || (edits >= 0 && distance >= edits) {
 					continue
 				}
-
-				results = append(results, searchResult{
-					scored: scored,
-					rank:   rank,
-				})
+				edits, best = distance, candidate
+			}
+			if edits < 0 {
+				total = -1
+				break
+			}
+			total += edits
+			if best == name {
+				matched = len(parts)
+			} else {
+				matched++
 			}
 		}
+
+		if total >= 0 {
+			scored.NameMatches, scored.CommandMatches = nil, nil
+			scored.Typo = true
+			results = append(results, typoResult{scored: scored, edits: total, extra: max(0, len(parts)-matched)})
+		}
 	}
 
-	// Sort by rank descending, then by frecency score descending
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].rank != results[j].rank {
-			return results[i].rank > results[j].rank
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].edits != results[j].edits {
+			return results[i].edits < results[j].edits
+		}
+		if results[i].extra != results[j].extra {
+			return results[i].extra < results[j].extra
 		}
 		return results[i].scored.FrecencyScore > results[j].scored.FrecencyScore
 	})
 
-	// Extract sorted scripts
-	var searchedScripts []ScoredScript
-	for _, result := range results {
-		searchedScripts = append(searchedScripts, result.scored)
+	matches := make([]ScoredScript, len(results))
+	for i, result := range results {
+		matches[i] = result.scored
 	}
+	return matches
+}
 
-	return searchedScripts
+// maxTypos is how many edits a word may be off by; short words must match exactly
+func maxTypos(word string) int {
+	length := len([]rune(word))
+	if length < typoMinWordLength {
+		return 0
+	}
+	return max(1, length/typoCharsPerEdit)
+}
+
+// isNameSeparator splits script names into parts: "test:e2e-ci" has test, e2e and ci
+func isNameSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// editDistance is the optimal string alignment distance: the number of insertions,
+// deletions, substitutions and swaps of adjacent characters turning a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
 }
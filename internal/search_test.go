@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -177,16 +178,16 @@ func TestSearchScriptsRankingWithFrecency(t *testing.T) {
 	now := time.Now()
 	scripts := []ScoredScript{
 		{
-			Script:       NPMScript{Name: "build", Command: "next build"},
+			Script:        NPMScript{Name: "build", Command: "next build"},
 			FrecencyScore: 10.0, // Higher frecency
-			LastUsed:     &now,
-			UseCount:     20,
+			LastUsed:      &now,
+			UseCount:      20,
 		},
 		{
-			Script:       NPMScript{Name: "build:prod", Command: "NODE_ENV=production next build"},
+			Script:        NPMScript{Name: "build:prod", Command: "NODE_ENV=production next build"},
 			FrecencyScore: 2.0, // Lower frecency
-			LastUsed:     &now,
-			UseCount:     3,
+			LastUsed:      &now,
+			UseCount:      3,
 		},
 	}
 
@@ -358,3 +359,141 @@ func getScriptNames(results []ScoredScript) []string {
 	}
 	return names
 }
+
+func TestSearchScriptsMatchPositions(t *testing.T) {
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "build:docker", Command: "docker build ."}},
+		{Script: NPMScript{Name: "up", Command: "docker compose up"}},
+	}
+
+	tests := []struct {
+		query          string
+		name           string
+		nameMatches    []int
+		commandMatches []int
+	}{
+		{"bdk", "build:docker", []int{0, 6, 9}, nil}, // Word starts beat the earliest "d"
+		{"dock", "build:docker", []int{6, 7, 8, 9}, nil},
+		{"compose", "up", nil, []int{7, 8, 9, 10, 11, 12, 13}},
+		{"docker build", "build:docker", []int{0, 1, 2, 3, 4, 6, 7, 8, 9, 10, 11}, nil},
+	}
+
+	for _, tt := range tests {
+		results := SearchScripts(scripts, tt.query)
+		if len(results) == 0 || results[0].Script.Name != tt.name {
+			t.Errorf("SearchScripts(%q): expected %s first, got %+v", tt.query, tt.name, results)
+			continue
+		}
+		if !reflect.DeepEqual(results[0].NameMatches, tt.nameMatches) || !reflect.DeepEqual(results[0].CommandMatches, tt.commandMatches) {
+			t.Errorf("SearchScripts(%q) matches = %v / %v, want %v / %v",
+				tt.query, results[0].NameMatches, results[0].CommandMatches, tt.nameMatches, tt.commandMatches)
+		}
+	}
+
+	// The unfiltered list carries no highlights
+	if results := SearchScripts(scripts, ""); results[0].NameMatches != nil {
+		t.Errorf("expected no matches without a query, got %v", results[0].NameMatches)
+	}
+}
+
+func TestSearchScriptsKeepsSameNameFromEachSource(t *testing.T) {
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "build", Command: "go build", Source: "make"}, FrecencyScore: 1},
+		{Script: NPMScript{Name: "build", Command: "vite build", Source: "npm"}, FrecencyScore: 5},
+		{Script: NPMScript{Name: "docker-build", Command: "docker build .", Source: "make"}},
+	}
+
+	for _, query := range []string{"build", "go build", "vite build"} {
+		results := SearchScripts(scripts, query)
+		if query == "build" && (len(results) != 3 || scriptKey(results[0]) != "build:npm" || scriptKey(results[1]) != "build:make") {
+			t.Errorf("SearchScripts(%q): expected both builds first, more frecent first, got %v", query, getScriptNames(results))
+		}
+		if query != "build" && len(results) != 1 {
+			t.Errorf("SearchScripts(%q): expected only the matching build, got %d results", query, len(results))
+		}
+	}
+}
+
+func TestFuzzyScorePrefersWordStarts(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"tw", "test:watch", "tsc --watch"},  // Both at word starts, the closer pair wins
+		{"sb", "storybook:build", "sandbox"}, // After a separator beats mid-word
+		{"tu", "testUnit", "status"},         // A camelCase hump beats mid-word
+	}
+
+	for _, tt := range tests {
+		betterScore, betterPositions := fuzzyScore([]rune(tt.better), []rune(tt.pattern))
+		worseScore, _ := fuzzyScore([]rune(tt.worse), []rune(tt.pattern))
+		if betterPositions == nil || betterScore < worseScore {
+			t.Errorf("fuzzyScore(%q): expected %q (%d) to score at least %q (%d)", tt.pattern, tt.better, betterScore, tt.worse, worseScore)
+		}
+	}
+
+	if _, positions := fuzzyScore([]rune("build"), []rune("dlb")); positions != nil {
+		t.Errorf("expected out-of-order characters not to match, got %v", positions)
+	}
+}
+
+func TestSearchScriptsToleratesTypos(t *testing.T) {
+	scripts := []ScoredScript{
+		{Script: NPMScript{Name: "test", Command: "vitest", Source: "npm"}, FrecencyScore: 1},
+		{Script: NPMScript{Name: "test:e2e", Command: "playwright test", Source: "npm"}, FrecencyScore: 5},
+		{Script: NPMScript{Name: "db:migrate", Command: "prisma migrate deploy", Source: "npm"}},
+		{Script: NPMScript{Name: "lint", Command: "eslint .", Source: "npm"}},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"tset", []string{"test", "test:e2e"}}, // Swapped letters; fewer typos first, then frecency
+		{"tets", []string{"test", "test:e2e"}},
+		{"mirgate", []string{"db:migrate"}},
+		{"db mgirate", []string{"db:migrate"}},
+		{"lnt", []string{"lint"}}, // Fuzzy match, not a typo
+		{"xyz", nil},
+		{"tsst lint", nil}, // Every word has to be close
+	}
+
+	for _, tt := range tests {
+		results := SearchScripts(scripts, tt.query)
+		if got := getScriptNames(results); !reflect.DeepEqual(got, tt.expected) && !(len(got) == 0 && len(tt.expected) == 0) {
+			t.Errorf("SearchScripts(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+		for _, scored := range results {
+			if scored.Typo != (tt.query != "lnt") {
+				t.Errorf("SearchScripts(%q): %s has Typo = %v", tt.query, scored.Script.Name, scored.Typo)
+			}
+		}
+	}
+
+	// Near misses are only a fallback: a real match hides them
+	if results := SearchScripts(scripts, "test"); len(results) != 2 || results[0].Typo {
+		t.Errorf("expected only exact matches for test, got %v", getScriptNames(results))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"test", "test", 0},
+		{"tset", "test", 1}, // Swap
+		{"tst", "test", 1},  // Missing
+		{"tesst", "test", 1},
+		{"best", "test", 1},
+		{"tsst", "lint", 3},
+		{"", "dev", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	RunHistoryLimit         = 500              // Recent runs consulted for context ranking and suggestions
+	suggestionMinConfidence = 0.6              // Share of past occasions a pattern must hold to be suggested
+	sequenceMinSamples      = 3                // Times a script must have been followed by another to learn from it
+	sequenceWindow          = 30 * time.Minute // A run this soon after another one "follows" it
+	timeOfDayMinDays        = 3                // Days a time pattern must have been seen on
+	timeOfDayWindowHours    = 1                // Hours either side of now that count as "around this time"
+)
+
+// Suggestion is the script the run history predicts you want next
+type Suggestion struct {
+	Script     ScoredScript
+	Reason     string  // Why it is suggested, e.g. "usually follows lint"
+	Confidence float64 // Share of past occasions the pattern held, 0-1
+}
+
+// SuggestNext looks for patterns in the run history (newest first, as returned by
+// GetProjectRuns): what usually follows the script that just finished, what is usually
+// run first each day, and what is usually run around this time. Returns the strongest
+// prediction among scripts, or nil if none is confident enough.
+func SuggestNext(scripts []ScoredScript, runs []ScriptRun, now time.Time) *Suggestion {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	history := make([]ScriptRun, len(runs))
+	copy(history, runs)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].StartedAt.Before(history[j].StartedAt)
+	})
+
+	candidates := []*Suggestion{
+		suggestFollowing(scripts, history, now),
+		suggestFirstOfDay(scripts, history, now),
+		suggestTimeOfDay(scripts, history, now),
+	}
+
+	var best *Suggestion
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.Confidence < suggestionMinConfidence {
+			continue
+		}
+		if best == nil || candidate.Confidence > best.Confidence {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// PickLast chooses the script -l runs without a search term: the suggestion when it is
+// confident, unless a script is pinned, since a pin is an explicit choice that wins
+// over predictions; otherwise the most frecent script. Returns nil without history,
+// and whether the suggestion was picked.
+func PickLast(scripts []ScoredScript, suggestion *Suggestion) (*ScoredScript, bool) {
+	mostFrecent := GetMostFrecent(scripts) // Pinned scripts sort first
+	if suggestion != nil && (mostFrecent == nil || !mostFrecent.IsPinned) {
+		return &suggestion.Script, true
+	}
+	return mostFrecent, false
+}
+
+// suggestFollowing predicts the script that usually runs next after the last one,
+// if that finished recently. Re-running the same script doesn't count as following it.
+func suggestFollowing(scripts []ScoredScript, history []ScriptRun, now time.Time) *Suggestion {
+	last := history[len(history)-1]
+	if now.Sub(last.StartedAt.Add(last.Duration)) > sequenceWindow {
+		return nil
+	}
+	lastKey := last.ScriptName + ":" + last.Source
+
+	followers := make(map[string]int)
+	total := 0
+	for i := 0; i < len(history)-1; i++ {
+		run, next := history[i], history[i+1]
+		if run.ScriptName+":"+run.Source != lastKey {
+			continue
+		}
+		nextKey := next.ScriptName + ":" + next.Source
+		if nextKey == lastKey || next.StartedAt.Sub(run.StartedAt.Add(run.Duration)) > sequenceWindow {
+			continue
+		}
+		followers[nextKey]++
+		total++
+	}
+	if total < sequenceMinSamples {
+		return nil
+	}
+
+	return bestCandidate(scripts, followers, total, "usually follows "+last.ScriptName)
+}
+
+// suggestFirstOfDay predicts the day's first script when nothing has run yet today
+func suggestFirstOfDay(scripts []ScoredScript, history []ScriptRun, now time.Time) *Suggestion {
+	today := startOfDay(now)
+	if !history[len(history)-1].StartedAt.Before(today) {
+		return nil // Already ran something today
+	}
+
+	firsts := make(map[string]int)
+	seen := make(map[time.Time]bool)
+	for _, run := range history {
+		day := startOfDay(run.StartedAt)
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		firsts[run.ScriptName+":"+run.Source]++
+	}
+	if len(seen) < timeOfDayMinDays {
+		return nil
+	}
+
+	return bestCandidate(scripts, firsts, len(seen), "usually your first script of the day")
+}
+
+// suggestTimeOfDay predicts a script that runs on most days around the current hour,
+// and mostly at this hour rather than all day long, unless it already ran today
+func suggestTimeOfDay(scripts []ScoredScript, history []ScriptRun, now time.Time) *Suggestion {
+	today := startOfDay(now)
+	days := make(map[time.Time]bool)                  // Past days with any run around this hour
+	scriptDays := make(map[string]map[time.Time]bool) // Same, per script
+	inWindow := make(map[string]int)
+	totalRuns := make(map[string]int)
+	ranToday := make(map[string]bool)
+
+	for _, run := range history {
+		key := run.ScriptName + ":" + run.Source
+		if !run.StartedAt.Before(today) {
+			ranToday[key] = true
+			continue
+		}
+		totalRuns[key]++
+		if !nearHour(run.StartedAt.Local().Hour(), now.Local().Hour()) {
+			continue
+		}
+		day := startOfDay(run.StartedAt)
+		days[day] = true
+		if scriptDays[key] == nil {
+			scriptDays[key] = make(map[time.Time]bool)
+		}
+		scriptDays[key][day] = true
+		inWindow[key]++
+	}
+	if len(days) < timeOfDayMinDays {
+		return nil
+	}
+
+	var best *Suggestion
+	for _, scored := range scripts {
+		key := scriptKey(scored)
+		ranOn := scriptDays[key]
+		if len(ranOn) < timeOfDayMinDays || ranToday[key] {
+			continue
+		}
+		coverage := float64(len(ranOn)) / float64(len(days))
+		concentration := float64(inWindow[key]) / float64(totalRuns[key])
+		confidence := coverage * concentration
+		if best == nil || confidence > best.Confidence {
+			best = &Suggestion{
+				Script:     scored,
+				Reason:     fmt.Sprintf("usually run around %d:00", now.Local().Hour()),
+				Confidence: confidence,
+			}
+		}
+	}
+	return best
+}
+
+// bestCandidate picks the most frequent of the counted scripts that still exist,
+// preferring the more frecent one on a tie
+func bestCandidate(scripts []ScoredScript, counts map[string]int, total int, reason string) *Suggestion {
+	var best *Suggestion
+	for _, scored := range scripts {
+		count := counts[scriptKey(scored)]
+		if count == 0 {
+			continue
+		}
+		confidence := float64(count) / float64(total)
+		if best == nil || confidence > best.Confidence {
+			best = &Suggestion{Script: scored, Reason: reason, Confidence: confidence}
+		}
+	}
+	return best
+}
+
+// startOfDay returns local midnight of the day t falls on
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Local().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// nearHour reports whether two hours of the day are within timeOfDayWindowHours,
+// wrapping around midnight
+func nearHour(a, b int) bool {
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return min(diff, 24-diff) <= timeOfDayWindowHours
+}
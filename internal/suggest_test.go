@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+// run builds a history entry for a script started at the given time
+func run(name string, startedAt time.Time) ScriptRun {
+	return ScriptRun{ScriptName: name, Source: "npm", StartedAt: startedAt, Duration: time.Minute}
+}
+
+func suggestScripts(names ...string) []ScoredScript {
+	scripts := make([]ScoredScript, len(names))
+	for i, name := range names {
+		scripts[i] = ScoredScript{Script: NPMScript{Name: name, Source: "npm"}}
+	}
+	return scripts
+}
+
+func TestSuggestNextFollowsLastScript(t *testing.T) {
+	scripts := suggestScripts("dev", "lint", "test")
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+	var runs []ScriptRun
+	for i := 0; i < 4; i++ {
+		start := day.Add(time.Duration(i)*24*time.Hour + 14*time.Hour)
+		runs = append(runs, run("lint", start), run("test", start.Add(5*time.Minute)))
+	}
+	lastLint := day.Add(5*24*time.Hour + 14*time.Hour)
+	runs = append(runs, run("lint", lastLint))
+
+	suggestion := SuggestNext(scripts, runs, lastLint.Add(2*time.Minute))
+	if suggestion == nil || suggestion.Script.Script.Name != "test" {
+		t.Fatalf("expected test to be suggested after lint, got %+v", suggestion)
+	}
+	if suggestion.Reason != "usually follows lint" {
+		t.Errorf("unexpected reason %q", suggestion.Reason)
+	}
+
+	// An hour later lint is no longer "just ran"
+	if suggestion := SuggestNext(scripts, runs, lastLint.Add(time.Hour)); suggestion != nil && suggestion.Reason == "usually follows lint" {
+		t.Errorf("expected no sequence suggestion long after lint, got %+v", suggestion)
+	}
+}
+
+func TestPickLastPrefersPins(t *testing.T) {
+	scripts := suggestScripts("dev", "lint", "test")
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+	var runs []ScriptRun
+	for i := 0; i < 4; i++ {
+		start := day.Add(time.Duration(i)*24*time.Hour + 14*time.Hour)
+		runs = append(runs, run("lint", start), run("test", start.Add(5*time.Minute)))
+	}
+	lastLint := day.Add(5*24*time.Hour + 14*time.Hour)
+	runs = append(runs, run("lint", lastLint))
+
+	suggestion := SuggestNext(scripts, runs, lastLint.Add(2*time.Minute))
+	if suggestion == nil || suggestion.Script.Script.Name != "test" {
+		t.Fatalf("expected test to be suggested after lint, got %+v", suggestion)
+	}
+
+	// Without pins, the confident suggestion wins over the most frecent script
+	scripts[1].FrecencyScore = 10
+	if pick, suggested := PickLast(scripts, suggestion); !suggested || pick.Script.Name != "test" {
+		t.Errorf("expected the suggested test, got %+v (suggested: %v)", pick, suggested)
+	}
+
+	// A pinned script always wins, even one that was never run
+	scripts[0].IsPinned = true
+	if pick, suggested := PickLast(scripts, suggestion); suggested || pick.Script.Name != "dev" {
+		t.Errorf("expected the pinned dev, got %+v (suggested: %v)", pick, suggested)
+	}
+}
+
+func TestSuggestNextFirstOfDay(t *testing.T) {
+	scripts := suggestScripts("dev", "docker:up")
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+	var runs []ScriptRun
+	for i := 0; i < 5; i++ {
+		morning := day.Add(time.Duration(i)*24*time.Hour + 9*time.Hour)
+		runs = append(runs, run("docker:up", morning), run("dev", morning.Add(10*time.Minute)), run("dev", morning.Add(4*time.Hour)))
+	}
+
+	// Next morning, before anything ran
+	suggestion := SuggestNext(scripts, runs, day.Add(5*24*time.Hour+8*time.Hour))
+	if suggestion == nil || suggestion.Script.Script.Name != "docker:up" {
+		t.Fatalf("expected docker:up first thing in the morning, got %+v", suggestion)
+	}
+}
+
+func TestSuggestNextTimeOfDay(t *testing.T) {
+	scripts := suggestScripts("dev", "report")
+	day := time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+	var runs []ScriptRun
+	for i := 0; i < 4; i++ {
+		date := day.Add(time.Duration(i) * 24 * time.Hour)
+		runs = append(runs, run("dev", date.Add(9*time.Hour)), run("dev", date.Add(13*time.Hour)), run("report", date.Add(17*time.Hour)))
+	}
+
+	// Late afternoon, after dev already ran today
+	today := day.Add(4 * 24 * time.Hour)
+	runs = append(runs, run("dev", today.Add(9*time.Hour)))
+	suggestion := SuggestNext(scripts, runs, today.Add(17*time.Hour))
+	if suggestion == nil || suggestion.Script.Script.Name != "report" {
+		t.Fatalf("expected report around 17:00, got %+v", suggestion)
+	}
+}
+
+func TestSuggestNextNeedsConfidence(t *testing.T) {
+	scripts := suggestScripts("lint", "test", "build")
+	start := time.Date(2025, 6, 2, 14, 0, 0, 0, time.Local)
+
+	// lint is followed by test and build equally often: no clear favourite
+	runs := []ScriptRun{
+		run("lint", start), run("test", start.Add(time.Minute*5)),
+		run("lint", start.Add(time.Hour)), run("build", start.Add(time.Hour+5*time.Minute)),
+		run("lint", start.Add(2*time.Hour)), run("test", start.Add(2*time.Hour+5*time.Minute)),
+		run("lint", start.Add(3*time.Hour)), run("build", start.Add(3*time.Hour+5*time.Minute)),
+		run("lint", start.Add(4*time.Hour)),
+	}
+	if suggestion := SuggestNext(scripts, runs, start.Add(4*time.Hour+2*time.Minute)); suggestion != nil {
+		t.Errorf("expected no suggestion for a 50/50 pattern, got %+v", suggestion)
+	}
+
+	// Scripts that no longer exist are never suggested
+	if suggestion := SuggestNext(suggestScripts("lint"), runs, start.Add(4*time.Hour+2*time.Minute)); suggestion != nil {
+		t.Errorf("expected no suggestion for removed scripts, got %+v", suggestion)
+	}
+}
@@ -0,0 +1,16 @@
+// Package termquery keeps Bubble Tea from asking the terminal for its background
+// color on every start.
+//
+// Bubble Tea v1 asks in its package init, so even commands that never draw a UI
+// (--list-names for shell completion, -l, --export) wrote an OSC 11 query to the
+// terminal and waited for the answer. Go initializes packages in import path order once
+// their dependencies are, so this init runs before Bubble Tea's, and a known background
+// turns that query into a no-op. The runner asks the terminal itself right before a UI
+// starts (see useTerminalBackground).
+package termquery
+
+import "github.com/charmbracelet/lipgloss"
+
+func init() {
+	lipgloss.SetHasDarkBackground(true) // Until a UI asks the terminal
+}
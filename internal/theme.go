@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+
+	_ "github.com/alexanderchan/alex-runner/internal/termquery" // Before Bubble Tea's init
+)
+
+// Built-in theme names (config "theme")
+const (
+	ThemeAuto         = "auto" // dark or light, following the terminal's background
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeMonochrome   = "monochrome" // Bold, faint, underline and reverse video only; used when NO_COLOR is set
+)
+
+const noColor = "none" // Theme color for the terminal's default, e.g. throughout monochrome
+
+// ThemeColors assigns a color to each part of the UI: "#71BEF2", an ANSI color number
+// like "240", or "none" for the terminal's default. Custom themes in the config file
+// start from Base (a built-in theme, auto by default) and may leave colors unset.
+type ThemeColors struct {
+	Base      string `json:"base,omitempty"`
+	Primary   string `json:"primary,omitempty"`   // Script names and titles
+	Command   string `json:"command,omitempty"`   // Commands
+	Muted     string `json:"muted,omitempty"`     // Run counts, hints and borders
+	Cursor    string `json:"cursor,omitempty"`    // The ❯ in front of the selected script
+	Selection string `json:"selection,omitempty"` // Background of the selected script ("none" reverses it)
+	Label     string `json:"label,omitempty"`     // Preview labels and group headers
+	Success   string `json:"success,omitempty"`   // Passed runs, make scripts and default answers
+	Failure   string `json:"failure,omitempty"`   // Failed runs
+	Highlight string `json:"highlight,omitempty"` // Matched characters and the suggested script
+}
+
+var darkColors = ThemeColors{
+	Primary:   "#66C2CD",
+	Command:   "250", // Lighter gray for better readability
+	Muted:     "240", // Darker gray for stars/run data
+	Cursor:    "#D290E4",
+	Selection: "#2A2A2A",
+	Label:     "#71BEF2",
+	Success:   "#A8CC8C",
+	Failure:   "#E88388",
+	Highlight: "#DBAB79",
+}
+
+var lightColors = ThemeColors{
+	Primary:   "#00838F",
+	Command:   "237",
+	Muted:     "245",
+	Cursor:    "#8E24AA",
+	Selection: "#E4E4E4",
+	Label:     "#1565C0",
+	Success:   "#2E7D32",
+	Failure:   "#C62828",
+	Highlight: "#B26A00",
+}
+
+var highContrastDarkColors = ThemeColors{
+	Primary:   "#00FFFF",
+	Command:   "#FFFFFF",
+	Muted:     "#C0C0C0",
+	Cursor:    "#FF00FF",
+	Selection: "#00005F",
+	Label:     "#5FAFFF",
+	Success:   "#00FF00",
+	Failure:   "#FF5F5F",
+	Highlight: "#FFFF00",
+}
+
+var highContrastLightColors = ThemeColors{
+	Primary:   "#005F5F",
+	Command:   "#000000",
+	Muted:     "#303030",
+	Cursor:    "#870087",
+	Selection: "#D7D7D7",
+	Label:     "#00005F",
+	Success:   "#005F00",
+	Failure:   "#AF0000",
+	Highlight: "#5F3F00",
+}
+
+var monochromeColors = ThemeColors{
+	Primary:   noColor,
+	Command:   noColor,
+	Muted:     noColor,
+	Cursor:    noColor,
+	Selection: noColor,
+	Label:     noColor,
+	Success:   noColor,
+	Failure:   noColor,
+	Highlight: noColor,
+}
+
+// themeVariants are the colors of a theme on light and on dark backgrounds
+type themeVariants struct {
+	light, dark ThemeColors
+}
+
+var builtinThemes = map[string]themeVariants{
+	ThemeAuto:         {light: lightColors, dark: darkColors},
+	ThemeDark:         {light: darkColors, dark: darkColors},
+	ThemeLight:        {light: lightColors, dark: lightColors},
+	ThemeHighContrast: {light: highContrastLightColors, dark: highContrastDarkColors},
+	ThemeMonochrome:   {light: monochromeColors, dark: monochromeColors},
+}
+
+// pendingTheme is the applied theme while its light or dark variant still depends on
+// the terminal background (see useTerminalBackground)
+var pendingTheme *themeVariants
+
+// ApplyTheme styles the UI with the theme named in the config: a built-in one or one
+// of its custom "themes". Themes with light and dark variants start dark; the terminal
+// is only asked for its background once a UI is about to start. NO_COLOR
+// (https://no-color.org) always selects monochrome. An unknown theme is reported and
+// auto used instead.
+func ApplyTheme(cfg *Config) error {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	name := cfg.Theme
+	if os.Getenv("NO_COLOR") != "" {
+		name = ThemeMonochrome
+	}
+
+	theme, err := resolveTheme(name, cfg.Themes)
+	setTheme(theme.dark)
+	pendingTheme = nil
+	if theme.light != theme.dark {
+		pendingTheme = &theme
+	}
+	return err
+}
+
+// useTerminalBackground switches to the light variant of the theme on a light terminal.
+// Asking the terminal writes a query to it and waits for the answer, so this is done
+// once, right before a UI is drawn on output, and never when output isn't a terminal
+// (--list-names, pipes).
+func useTerminalBackground(output *os.File) {
+	if pendingTheme == nil || !term.IsTerminal(output.Fd()) {
+		return
+	}
+	theme := *pendingTheme
+	pendingTheme = nil
+	dark := lipgloss.NewRenderer(output).HasDarkBackground()
+	lipgloss.SetHasDarkBackground(dark) // Adaptive colors of the huh prompts follow the answer
+	if !dark {
+		setTheme(theme.light)
+	}
+}
+
+// resolveTheme looks up a theme by name, custom themes first. Unset colors of a
+// custom theme come from its base.
+func resolveTheme(name string, custom map[string]ThemeColors) (themeVariants, error) {
+	if name == "" {
+		name = ThemeAuto
+	}
+
+	if colors, ok := custom[name]; ok {
+		baseName := colors.Base
+		if baseName == "" {
+			baseName = ThemeAuto
+		}
+		base, ok := builtinThemes[strings.ToLower(baseName)]
+		if !ok {
+			return builtinThemes[ThemeAuto], fmt.Errorf("theme '%s' is based on unknown theme '%s' (use %s)", name, colors.Base, builtinThemeNames())
+		}
+		return themeVariants{light: colors.over(base.light), dark: colors.over(base.dark)}, nil
+	}
+
+	if theme, ok := builtinThemes[strings.ToLower(name)]; ok {
+		return theme, nil
+	}
+	return builtinThemes[ThemeAuto], fmt.Errorf("unknown theme '%s' (use %s, or define it under \"themes\")", name, builtinThemeNames())
+}
+
+// over returns base with the colors set in c replacing its own
+func (c ThemeColors) over(base ThemeColors) ThemeColors {
+	pick := func(color, fallback string) string {
+		if color != "" {
+			return color
+		}
+		return fallback
+	}
+	return ThemeColors{
+		Primary:   pick(c.Primary, base.Primary),
+		Command:   pick(c.Command, base.Command),
+		Muted:     pick(c.Muted, base.Muted),
+		Cursor:    pick(c.Cursor, base.Cursor),
+		Selection: pick(c.Selection, base.Selection),
+		Label:     pick(c.Label, base.Label),
+		Success:   pick(c.Success, base.Success),
+		Failure:   pick(c.Failure, base.Failure),
+		Highlight: pick(c.Highlight, base.Highlight),
+	}
+}
+
+// builtinThemeNames lists the built-in themes for error messages
+func builtinThemeNames() string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// themeColor turns a theme color into a lipgloss color
+func themeColor(color string) lipgloss.TerminalColor {
+	if color == noColor || color == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(color)
+}
+
+// formTheme styles the huh prompts (confirmations, Makefile parameters)
+var formTheme = huh.ThemeCharm()
+
+func init() {
+	setTheme(darkColors) // Until ApplyTheme has looked at the config and terminal
+}
+
+// setTheme rebuilds the UI styles from a theme's colors
+func setTheme(colors ThemeColors) {
+	primary := themeColor(colors.Primary)
+	command := themeColor(colors.Command)
+	muted := themeColor(colors.Muted)
+	label := themeColor(colors.Label)
+	success := themeColor(colors.Success)
+	highlight := themeColor(colors.Highlight)
+
+	scriptNameStyle = lipgloss.NewStyle().Bold(true).Foreground(primary)
+	commandStyle = lipgloss.NewStyle().Foreground(command)
+	metadataStyle = lipgloss.NewStyle().Foreground(muted)
+	if colors.Muted == noColor {
+		metadataStyle = metadataStyle.Faint(true) // Still set apart without colors
+	}
+	cursorStyle = lipgloss.NewStyle().Foreground(themeColor(colors.Cursor))
+	promptStyle = lipgloss.NewStyle().Bold(true).Foreground(primary)
+	defaultAnswerStyle = lipgloss.NewStyle().Bold(true).Foreground(success)
+	previewLabelStyle = lipgloss.NewStyle().Foreground(label)
+	previewBorderStyle = lipgloss.NewStyle().BorderForeground(muted)
+	groupHeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(label)
+	successStyle = lipgloss.NewStyle().Foreground(success)
+	failureStyle = lipgloss.NewStyle().Foreground(themeColor(colors.Failure))
+	suggestionStyle = lipgloss.NewStyle().Foreground(highlight)
+
+	// Characters matched by the search query
+	nameMatchStyle = scriptNameStyle.Foreground(highlight).Underline(true)
+	commandMatchStyle = commandStyle.Foreground(highlight).Underline(true)
+
+	// Selected line backgrounds, or reverse video without colors
+	selectedScriptNameBgStyle = lipgloss.NewStyle().Background(themeColor(colors.Selection))
+	if colors.Selection == noColor {
+		selectedScriptNameBgStyle = lipgloss.NewStyle().Reverse(true)
+	}
+	selectedCommandBgStyle = selectedScriptNameBgStyle
+
+	formTheme = huh.ThemeCharm()
+	if colors == monochromeColors {
+		formTheme = huh.ThemeBase()
+	}
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestResolveTheme(t *testing.T) {
+	custom := map[string]ThemeColors{
+		"ocean":  {Base: "dark", Primary: "#0077BE", Selection: "none"},
+		"broken": {Base: "neon"},
+	}
+
+	theme, err := resolveTheme("ocean", custom)
+	if err != nil {
+		t.Fatalf("resolveTheme() error = %v", err)
+	}
+	if theme.dark.Primary != "#0077BE" || theme.dark.Selection != noColor {
+		t.Errorf("custom colors not applied: %+v", theme.dark)
+	}
+	if theme.dark.Command != darkColors.Command || theme.light != theme.dark {
+		t.Errorf("unset colors should come from the dark theme: %+v", theme)
+	}
+
+	if theme, err := resolveTheme("", nil); err != nil || theme != builtinThemes[ThemeAuto] {
+		t.Errorf("resolveTheme(\"\") = %+v, %v, want auto", theme, err)
+	}
+	if theme, err := resolveTheme("High-Contrast", nil); err != nil || theme.dark != highContrastDarkColors {
+		t.Errorf("resolveTheme(High-Contrast) = %+v, %v", theme, err)
+	}
+
+	for _, name := range []string{"neon", "broken"} {
+		theme, err := resolveTheme(name, custom)
+		if err == nil {
+			t.Errorf("resolveTheme(%s) should fail", name)
+		}
+		if theme != builtinThemes[ThemeAuto] {
+			t.Errorf("resolveTheme(%s) should fall back to auto", name)
+		}
+	}
+}
+
+func TestApplyThemeHonorsNoColor(t *testing.T) {
+	t.Cleanup(func() { setTheme(darkColors) })
+
+	cfg := DefaultConfig()
+	cfg.Theme = ThemeDark
+	if err := ApplyTheme(cfg); err != nil {
+		t.Fatalf("ApplyTheme() error = %v", err)
+	}
+	if selectedScriptNameBgStyle.GetBackground() != lipgloss.Color(darkColors.Selection) {
+		t.Errorf("dark selection background = %v", selectedScriptNameBgStyle.GetBackground())
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if err := ApplyTheme(cfg); err != nil {
+		t.Fatalf("ApplyTheme() error = %v", err)
+	}
+	if _, ok := scriptNameStyle.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("NO_COLOR should drop colors, got %v", scriptNameStyle.GetForeground())
+	}
+	if !selectedScriptNameBgStyle.GetReverse() || !metadataStyle.GetFaint() {
+		t.Error("without colors the selection should be reversed and metadata faint")
+	}
+}
+
+func TestApplyThemeDefersBackgroundQuery(t *testing.T) {
+	t.Cleanup(func() {
+		setTheme(darkColors)
+		pendingTheme = nil
+	})
+
+	// Applying auto only starts dark; nothing is asked yet
+	if err := ApplyTheme(DefaultConfig()); err != nil {
+		t.Fatalf("ApplyTheme() error = %v", err)
+	}
+	if pendingTheme == nil || scriptNameStyle.GetForeground() != lipgloss.Color(darkColors.Primary) {
+		t.Fatal("auto should start dark and leave the background to be checked")
+	}
+
+	// Output that isn't a terminal is never asked
+	file, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	useTerminalBackground(file)
+	if pendingTheme == nil {
+		t.Error("the background should only be checked on a terminal")
+	}
+	if info, _ := file.Stat(); info.Size() != 0 {
+		t.Errorf("expected nothing written to a non-terminal, got %d bytes", info.Size())
+	}
+
+	// A theme without light and dark variants never needs the background
+	cfg := DefaultConfig()
+	cfg.Theme = ThemeDark
+	ApplyTheme(cfg)
+	if pendingTheme != nil {
+		t.Error("dark doesn't depend on the background")
+	}
+}
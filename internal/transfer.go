@@ -0,0 +1,509 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	exportFormatName    = "alex-runner-export"
+	exportFormatVersion = 1 // Bump when fields change meaning; new optional fields don't need a bump
+)
+
+// ExportFormat selects how an export is written
+type ExportFormat string
+
+const (
+	ExportJSON   ExportFormat = "json"   // One indented JSON document
+	ExportNDJSON ExportFormat = "ndjson" // A header line, then one record per line
+)
+
+// ExportFormatForPath picks NDJSON for .ndjson/.jsonl files and JSON otherwise
+func ExportFormatForPath(path string) ExportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return ExportNDJSON
+	default:
+		return ExportJSON
+	}
+}
+
+// MergeStrategy decides how imported usage combines with existing usage of the same script
+type MergeStrategy string
+
+const (
+	MergeSum       MergeStrategy = "sum"       // Add run counts (for combining two machines)
+	MergeMax       MergeStrategy = "max"       // Keep the larger run count (safe to import twice)
+	MergeOverwrite MergeStrategy = "overwrite" // Replace existing rows with the imported ones
+)
+
+// ParseMergeStrategy validates a --merge-strategy value
+func ParseMergeStrategy(value string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(strings.ToLower(value)); strategy {
+	case MergeSum, MergeMax, MergeOverwrite:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy '%s' (use sum, max or overwrite)", value)
+	}
+}
+
+// PathRewrite maps directories under From to the same place under To
+type PathRewrite struct {
+	From string
+	To   string
+}
+
+// ParsePathRewrite parses a --rewrite-path value of the form "old=new"
+func ParsePathRewrite(value string) (PathRewrite, error) {
+	from, to, ok := strings.Cut(value, "=")
+	if !ok || from == "" || to == "" {
+		return PathRewrite{}, fmt.Errorf("invalid path rewrite '%s' (expected old=new)", value)
+	}
+	return PathRewrite{From: filepath.Clean(from), To: filepath.Clean(to)}, nil
+}
+
+// RewritePath applies the rule with the longest matching prefix; only whole
+// path components match, so /code doesn't rewrite /codebase
+func RewritePath(path string, rewrites []PathRewrite) string {
+	best := -1
+	for i, rewrite := range rewrites {
+		if path != rewrite.From && !strings.HasPrefix(path, rewrite.From+string(filepath.Separator)) {
+			continue
+		}
+		if best < 0 || len(rewrite.From) > len(rewrites[best].From) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return path
+	}
+	return rewrites[best].To + strings.TrimPrefix(path, rewrites[best].From)
+}
+
+// ExportData is everything alex-runner knows, in a portable form
+type ExportData struct {
+	Format          string                 `json:"format"`
+	Version         int                    `json:"version"`
+	ExportedAt      time.Time              `json:"exported_at"`
+	Usage           []ExportedUsage        `json:"usage"`
+	Runs            []ExportedRun          `json:"runs"`
+	MakeVariables   []ExportedMakeVariable `json:"make_variables"`
+	PackageManagers []ExportedCacheEntry   `json:"package_managers"`
+}
+
+// ExportedUsage is a script_usage row (frecency data and pin)
+type ExportedUsage struct {
+	Directory  string    `json:"directory"`
+	ScriptName string    `json:"script_name"`
+	Source     string    `json:"source"`
+	LastUsed   time.Time `json:"last_used"`
+	UseCount   int       `json:"use_count"`
+	IsPinned   bool      `json:"is_pinned,omitempty"`
+}
+
+// ExportedRun is a script_runs row
+type ExportedRun struct {
+	Directory    string    `json:"directory"`
+	ScriptName   string    `json:"script_name"`
+	Source       string    `json:"source"`
+	Command      string    `json:"command,omitempty"`
+	Args         string    `json:"args,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	ExitCode     int       `json:"exit_code"`
+	Branch       string    `json:"branch,omitempty"`
+	ChangedPaths []string  `json:"changed_paths,omitempty"`
+}
+
+// ExportedMakeVariable is a remembered Makefile variable value
+type ExportedMakeVariable struct {
+	Directory string    `json:"directory"`
+	Target    string    `json:"target"`
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportedCacheEntry is a package_manager_cache row
+type ExportedCacheEntry struct {
+	Directory      string    `json:"directory"`
+	PackageManager string    `json:"package_manager"`
+	DetectedAt     time.Time `json:"detected_at"`
+}
+
+// ImportOptions controls how an export is merged into the database
+type ImportOptions struct {
+	Strategy MergeStrategy
+	Rewrites []PathRewrite
+}
+
+// ImportReport counts the records that were written
+type ImportReport struct {
+	Usage           int
+	Runs            int
+	MakeVariables   int
+	PackageManagers int
+}
+
+// Export reads all history, pins, remembered variables and caches
+func (d *Database) Export() (*ExportData, error) {
+	data := &ExportData{
+		Format:     exportFormatName,
+		Version:    exportFormatVersion,
+		ExportedAt: time.Now(),
+	}
+
+	rows, err := d.db.Query(`SELECT directory, script_name, COALESCE(source, ''), last_used, use_count, COALESCE(is_pinned, 0) FROM script_usage ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export usage: %w", err)
+	}
+	for rows.Next() {
+		var usage ExportedUsage
+		var isPinned int
+		if err := rows.Scan(&usage.Directory, &usage.ScriptName, &usage.Source, &usage.LastUsed, &usage.UseCount, &isPinned); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		usage.IsPinned = isPinned != 0
+		data.Usage = append(data.Usage, usage)
+	}
+	rows.Close()
+
+	rows, err = d.db.Query(`SELECT directory, script_name, COALESCE(source, ''), COALESCE(command, ''), COALESCE(args, ''), started_at, duration_ms, exit_code, COALESCE(branch, ''), COALESCE(changed_paths, '') FROM script_runs ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export runs: %w", err)
+	}
+	for rows.Next() {
+		var run ExportedRun
+		var changedPaths string
+		if err := rows.Scan(&run.Directory, &run.ScriptName, &run.Source, &run.Command, &run.Args, &run.StartedAt, &run.DurationMs, &run.ExitCode, &run.Branch, &changedPaths); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		run.ChangedPaths = splitContextPaths(changedPaths)
+		data.Runs = append(data.Runs, run)
+	}
+	rows.Close()
+
+	rows, err = d.db.Query(`SELECT directory, target, name, value, updated_at FROM make_variables ORDER BY directory, target, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export make variables: %w", err)
+	}
+	for rows.Next() {
+		var variable ExportedMakeVariable
+		if err := rows.Scan(&variable.Directory, &variable.Target, &variable.Name, &variable.Value, &variable.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan make variable: %w", err)
+		}
+		data.MakeVariables = append(data.MakeVariables, variable)
+	}
+	rows.Close()
+
+	rows, err = d.cache.Query(`SELECT directory, package_manager, detected_at FROM package_manager_cache ORDER BY directory`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export package manager cache: %w", err)
+	}
+	for rows.Next() {
+		var entry ExportedCacheEntry
+		if err := rows.Scan(&entry.Directory, &entry.PackageManager, &entry.DetectedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan package manager cache: %w", err)
+		}
+		data.PackageManagers = append(data.PackageManagers, entry)
+	}
+	rows.Close()
+
+	return data, nil
+}
+
+// WriteExport writes an export as JSON or NDJSON
+func WriteExport(w io.Writer, data *ExportData, format ExportFormat) error {
+	if format == ExportJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	encoder := json.NewEncoder(w)
+	records := []any{map[string]any{
+		"type":        "header",
+		"format":      data.Format,
+		"version":     data.Version,
+		"exported_at": data.ExportedAt,
+	}}
+	for _, usage := range data.Usage {
+		records = append(records, struct {
+			Type string `json:"type"`
+			ExportedUsage
+		}{"usage", usage})
+	}
+	for _, run := range data.Runs {
+		records = append(records, struct {
+			Type string `json:"type"`
+			ExportedRun
+		}{"run", run})
+	}
+	for _, variable := range data.MakeVariables {
+		records = append(records, struct {
+			Type string `json:"type"`
+			ExportedMakeVariable
+		}{"make_variable", variable})
+	}
+	for _, entry := range data.PackageManagers {
+		records = append(records, struct {
+			Type string `json:"type"`
+			ExportedCacheEntry
+		}{"package_manager", entry})
+	}
+
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadExport reads an export written by WriteExport in either format
+func ReadExport(r io.Reader) (*ExportData, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	// NDJSON starts with a one-line header record
+	firstLine, _, _ := bytes.Cut(bytes.TrimSpace(content), []byte("\n"))
+	var header struct {
+		Type string `json:"type"`
+	}
+	var data *ExportData
+	if json.Unmarshal(firstLine, &header) == nil && header.Type == "header" {
+		data, err = readNDJSON(content)
+	} else {
+		data = &ExportData{}
+		err = json.Unmarshal(content, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+
+	if data.Format != exportFormatName {
+		return nil, fmt.Errorf("not an alex-runner export (format %q)", data.Format)
+	}
+	if data.Version > exportFormatVersion {
+		return nil, fmt.Errorf("export has version %d, this binary supports up to %d; please upgrade alex-runner",
+			data.Version, exportFormatVersion)
+	}
+	return data, nil
+}
+
+// readNDJSON decodes the line-per-record export format
+func readNDJSON(content []byte) (*ExportData, error) {
+	data := &ExportData{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		var err error
+		switch record.Type {
+		case "header":
+			err = json.Unmarshal(line, data)
+		case "usage":
+			var usage ExportedUsage
+			err = json.Unmarshal(line, &usage)
+			data.Usage = append(data.Usage, usage)
+		case "run":
+			var run ExportedRun
+			err = json.Unmarshal(line, &run)
+			data.Runs = append(data.Runs, run)
+		case "make_variable":
+			var variable ExportedMakeVariable
+			err = json.Unmarshal(line, &variable)
+			data.MakeVariables = append(data.MakeVariables, variable)
+		case "package_manager":
+			var entry ExportedCacheEntry
+			err = json.Unmarshal(line, &entry)
+			data.PackageManagers = append(data.PackageManagers, entry)
+		default:
+			// Record types from newer versions are skipped
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return data, scanner.Err()
+}
+
+// Import merges an export into the database in a single transaction
+func (d *Database) Import(data *ExportData, opts ImportOptions) (ImportReport, error) {
+	var report ImportReport
+	if opts.Strategy == "" {
+		opts.Strategy = MergeSum
+	}
+
+	err := d.withTx(func(tx *sql.Tx) error {
+		for _, usage := range data.Usage {
+			usage.Directory = RewritePath(usage.Directory, opts.Rewrites)
+			if err := importUsage(tx, usage, opts.Strategy); err != nil {
+				return err
+			}
+			report.Usage++
+		}
+
+		for _, run := range data.Runs {
+			run.Directory = RewritePath(run.Directory, opts.Rewrites)
+			inserted, err := importRun(tx, run)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				report.Runs++
+			}
+		}
+
+		for _, variable := range data.MakeVariables {
+			variable.Directory = RewritePath(variable.Directory, opts.Rewrites)
+			if err := importMakeVariable(tx, variable, opts.Strategy); err != nil {
+				return err
+			}
+			report.MakeVariables++
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	// Caches live in their own database; they are only filled in where missing
+	// unless overwriting, since the local detection is more likely to be current
+	for _, entry := range data.PackageManagers {
+		entry.Directory = RewritePath(entry.Directory, opts.Rewrites)
+		query := `INSERT INTO package_manager_cache (directory, package_manager, detected_at) VALUES (?, ?, ?) ON CONFLICT(directory) DO NOTHING`
+		if opts.Strategy == MergeOverwrite {
+			query = `INSERT OR REPLACE INTO package_manager_cache (directory, package_manager, detected_at) VALUES (?, ?, ?)`
+		}
+		if _, err := d.cache.Exec(query, entry.Directory, entry.PackageManager, entry.DetectedAt); err != nil {
+			return report, fmt.Errorf("failed to import package manager cache: %w", err)
+		}
+		report.PackageManagers++
+	}
+
+	return report, nil
+}
+
+// importUsage merges one usage row according to the strategy
+// Timestamps are compared in Go since stored values may carry different UTC offsets
+func importUsage(tx *sql.Tx, usage ExportedUsage, strategy MergeStrategy) error {
+	var existing ExportedUsage
+	var isPinnedInt int
+	err := tx.QueryRow(`SELECT last_used, use_count, COALESCE(is_pinned, 0) FROM script_usage WHERE directory = ? AND script_name = ? AND source = ?`,
+		usage.Directory, usage.ScriptName, usage.Source).Scan(&existing.LastUsed, &existing.UseCount, &isPinnedInt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read existing usage of %s: %w", usage.ScriptName, err)
+	}
+
+	merged := usage
+	if err == nil {
+		existing.IsPinned = isPinnedInt != 0
+		switch strategy {
+		case MergeSum:
+			merged.UseCount = existing.UseCount + usage.UseCount
+		case MergeMax:
+			merged.UseCount = max(existing.UseCount, usage.UseCount)
+		case MergeOverwrite:
+			// Imported row wins as is
+		default:
+			return fmt.Errorf("unknown merge strategy '%s'", strategy)
+		}
+		if strategy != MergeOverwrite {
+			if existing.LastUsed.After(usage.LastUsed) {
+				merged.LastUsed = existing.LastUsed
+			}
+			merged.IsPinned = existing.IsPinned || usage.IsPinned
+		}
+	}
+
+	isPinned := 0
+	if merged.IsPinned {
+		isPinned = 1
+	}
+	_, err = tx.Exec(`
+	INSERT INTO script_usage (directory, script_name, source, last_used, use_count, is_pinned)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(directory, script_name, source)
+	DO UPDATE SET last_used = excluded.last_used, use_count = excluded.use_count, is_pinned = excluded.is_pinned
+	`, merged.Directory, merged.ScriptName, merged.Source, merged.LastUsed, merged.UseCount, isPinned)
+	if err != nil {
+		return fmt.Errorf("failed to import usage of %s: %w", usage.ScriptName, err)
+	}
+	return nil
+}
+
+// importRun adds a run unless the same run (script and start time) is already recorded,
+// so importing a file twice doesn't duplicate history
+func importRun(tx *sql.Tx, run ExportedRun) (bool, error) {
+	var exists int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM script_runs WHERE directory = ? AND script_name = ? AND source = ? AND started_at = ?`,
+		run.Directory, run.ScriptName, run.Source, run.StartedAt).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existing run: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO script_runs (directory, script_name, source, command, args, started_at, duration_ms, exit_code, branch, changed_paths) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Directory, run.ScriptName, run.Source, run.Command, run.Args, run.StartedAt, run.DurationMs, run.ExitCode, run.Branch, joinContextPaths(run.ChangedPaths))
+	if err != nil {
+		return false, fmt.Errorf("failed to import run: %w", err)
+	}
+	return true, nil
+}
+
+// importMakeVariable keeps the most recently entered value, or the imported one when overwriting
+func importMakeVariable(tx *sql.Tx, variable ExportedMakeVariable, strategy MergeStrategy) error {
+	if strategy != MergeOverwrite {
+		var updatedAt time.Time
+		err := tx.QueryRow(`SELECT updated_at FROM make_variables WHERE directory = ? AND target = ? AND name = ?`,
+			variable.Directory, variable.Target, variable.Name).Scan(&updatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read existing make variable %s: %w", variable.Name, err)
+		}
+		if err == nil && !variable.UpdatedAt.After(updatedAt) {
+			return nil // Local value is newer
+		}
+	}
+
+	_, err := tx.Exec(`
+	INSERT INTO make_variables (directory, target, name, value, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(directory, target, name)
+	DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, variable.Directory, variable.Target, variable.Name, variable.Value, variable.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to import make variable %s: %w", variable.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRewritePath(t *testing.T) {
+	rewrites := []PathRewrite{
+		{From: "/Users/alice/code", To: "/home/alice/src"},
+		{From: "/Users/alice/code/work", To: "/work"},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/Users/alice/code", "/home/alice/src"},
+		{"/Users/alice/code/app", "/home/alice/src/app"},
+		{"/Users/alice/code/work/api", "/work/api"}, // Longest prefix wins
+		{"/Users/alice/codebase", "/Users/alice/codebase"},
+		{"/opt/other", "/opt/other"},
+	}
+
+	for _, tt := range tests {
+		if got := RewritePath(tt.path, rewrites); got != tt.expected {
+			t.Errorf("RewritePath(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestParsePathRewrite(t *testing.T) {
+	rewrite, err := ParsePathRewrite("/Users/alice/code/=/home/alice/src")
+	if err != nil {
+		t.Fatalf("ParsePathRewrite() error = %v", err)
+	}
+	if rewrite.From != "/Users/alice/code" || rewrite.To != "/home/alice/src" {
+		t.Errorf("unexpected rewrite %+v", rewrite)
+	}
+
+	for _, invalid := range []string{"/no/separator", "=/only/new", "/only/old="} {
+		if _, err := ParsePathRewrite(invalid); err == nil {
+			t.Errorf("ParsePathRewrite(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for _, valid := range []string{"sum", "MAX", "overwrite"} {
+		if _, err := ParseMergeStrategy(valid); err != nil {
+			t.Errorf("ParseMergeStrategy(%q) error = %v", valid, err)
+		}
+	}
+	if _, err := ParseMergeStrategy("average"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func createExportSource(t *testing.T) *Database {
+	db, _ := setupTestDB(t)
+
+	for i := 0; i < 3; i++ {
+		if err := db.RecordUsage("/Users/alice/code/app", "dev", "pnpm"); err != nil {
+			t.Fatalf("RecordUsage() error = %v", err)
+		}
+	}
+	if err := db.PinScript("/Users/alice/code/app", "build", "make"); err != nil {
+		t.Fatalf("PinScript() error = %v", err)
+	}
+	err := db.RecordRun(ScriptRun{
+		Directory:  "/Users/alice/code/app",
+		ScriptName: "dev",
+		Source:     "pnpm",
+		Args:       "--port 3001",
+		StartedAt:  time.Now().Add(-time.Hour),
+		Duration:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+	if err := db.SaveMakeVariables("/Users/alice/code/app", "deploy", map[string]string{"ENV": "staging"}); err != nil {
+		t.Fatalf("SaveMakeVariables() error = %v", err)
+	}
+	if err := db.SetCachedPackageManager("/Users/alice/code/app", "pnpm"); err != nil {
+		t.Fatalf("SetCachedPackageManager() error = %v", err)
+	}
+
+	return db
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []ExportFormat{ExportJSON, ExportNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			source := createExportSource(t)
+			defer source.Close()
+
+			data, err := source.Export()
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteExport(&buf, data, format); err != nil {
+				t.Fatalf("WriteExport() error = %v", err)
+			}
+
+			imported, err := ReadExport(&buf)
+			if err != nil {
+				t.Fatalf("ReadExport() error = %v", err)
+			}
+
+			target, _ := setupTestDB(t)
+			defer target.Close()
+
+			report, err := target.Import(imported, ImportOptions{
+				Strategy: MergeSum,
+				Rewrites: []PathRewrite{{From: "/Users/alice/code", To: "/home/alice/src"}},
+			})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if report.Usage != 2 || report.Runs != 1 || report.MakeVariables != 1 || report.PackageManagers != 1 {
+				t.Errorf("unexpected import report %+v", report)
+			}
+
+			stats, _ := target.GetUsageStats("/home/alice/src/app")
+			if len(stats) != 2 {
+				t.Fatalf("expected 2 usage rows under the rewritten path, got %d", len(stats))
+			}
+			if isPinned, _ := target.IsPinned("/home/alice/src/app", "build", "make"); !isPinned {
+				t.Error("expected pin to be imported")
+			}
+
+			runs, _ := target.GetRecentRuns("/home/alice/src/app", "dev", "pnpm", 5)
+			if len(runs) != 1 || runs[0].Args != "--port 3001" || runs[0].Duration != 2*time.Second {
+				t.Errorf("unexpected imported runs %+v", runs)
+			}
+
+			variables, _ := target.GetMakeVariables("/home/alice/src/app", "deploy")
+			if variables["ENV"] != "staging" {
+				t.Errorf("expected ENV=staging, got %v", variables)
+			}
+
+			if pm, _ := target.GetCachedPackageManager("/home/alice/src/app"); pm != "pnpm" {
+				t.Errorf("expected cached pnpm, got %q", pm)
+			}
+		})
+	}
+}
+
+func TestImportMergeStrategies(t *testing.T) {
+	tests := []struct {
+		strategy      MergeStrategy
+		expectedCount int
+	}{
+		{MergeSum, 7},
+		{MergeMax, 5},
+		{MergeOverwrite, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			db, _ := setupTestDB(t)
+			defer db.Close()
+
+			for i := 0; i < 5; i++ {
+				if err := db.RecordUsage("/test", "dev", "npm"); err != nil {
+					t.Fatalf("RecordUsage() error = %v", err)
+				}
+			}
+
+			older := time.Now().Add(-48 * time.Hour)
+			data := &ExportData{
+				Format:  exportFormatName,
+				Version: exportFormatVersion,
+				Usage: []ExportedUsage{
+					{Directory: "/test", ScriptName: "dev", Source: "npm", LastUsed: older, UseCount: 2},
+				},
+			}
+
+			if _, err := db.Import(data, ImportOptions{Strategy: tt.strategy}); err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			stats, _ := db.GetUsageStats("/test")
+			if stats[0].UseCount != tt.expectedCount {
+				t.Errorf("use count = %d, want %d", stats[0].UseCount, tt.expectedCount)
+			}
+
+			keptNewer := stats[0].LastUsed.After(older.Add(time.Hour))
+			if tt.strategy == MergeOverwrite && keptNewer {
+				t.Error("overwrite should replace last_used with the imported value")
+			}
+			if tt.strategy != MergeOverwrite && !keptNewer {
+				t.Error("expected the newer local last_used to be kept")
+			}
+		})
+	}
+}
+
+func TestImportRunsTwiceDoesNotDuplicate(t *testing.T) {
+	source := createExportSource(t)
+	defer source.Close()
+	data, _ := source.Export()
+
+	target, _ := setupTestDB(t)
+	defer target.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := target.Import(data, ImportOptions{Strategy: MergeMax}); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+	}
+
+	runs, _ := target.GetRecentRuns("/Users/alice/code/app", "dev", "pnpm", 10)
+	if len(runs) != 1 {
+		t.Errorf("expected 1 run after importing twice, got %d", len(runs))
+	}
+	stats, _ := target.GetUsageStats("/Users/alice/code/app")
+	for _, usage := range stats {
+		if usage.ScriptName == "dev" && usage.UseCount != 3 {
+			t.Errorf("max strategy should be idempotent, got use count %d", usage.UseCount)
+		}
+	}
+}
+
+func TestReadExportRejectsNewerVersion(t *testing.T) {
+	input := `{"format": "alex-runner-export", "version": 99}`
+	if _, err := ReadExport(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "upgrade") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+
+	if _, err := ReadExport(strings.NewReader(`{"hello": "world"}`)); err == nil {
+		t.Error("expected an error for a file that isn't an export")
+	}
+}
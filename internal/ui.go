@@ -1,15 +1,25 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
 )
 
 // UI Configuration Constants
@@ -18,78 +28,60 @@ const (
 	debugMode = false // Set to true to show viewport/scroll debug info
 
 	// Viewport sizing
-	minViewportHeight     = 5   // Minimum height for the viewport in small terminals
-	maxViewportHeight     = 0   // Maximum height (0 = no limit, use full terminal)
-	headerFooterLines     = 6   // Lines reserved for title (2), filter (2), help (2)
-	linesPerScriptOption  = 2   // Lines each script takes (name, command+metadata)
+	minViewportHeight    = 5                                                         // Minimum height for the viewport in small terminals
+	maxViewportHeight    = 0                                                         // Maximum height (0 = no limit, use full terminal)
+	headerFooterLines    = 6                                                         // Lines reserved for title (2), filter (2), help (2)
+	chainPreviewLines    = 1                                                         // Line reserved for the execution chain of the selected script
+	linesPerScriptOption = 2                                                         // Lines each script takes (name, command+metadata)
+	minInlineHeight      = headerFooterLines + chainPreviewLines + minViewportHeight // Smallest inline selector
 
 	// Text input sizing
-	filterCharLimit       = 100 // Maximum characters in filter input
-	filterPromptWidth     = 3   // Width of "/ " prompt plus spacing
+	filterCharLimit   = 100 // Maximum characters in filter input
+	filterPromptWidth = 3   // Width of "/ " prompt plus spacing
 
 	// Command truncation
-	commandMaxWidthBuffer = 5   // Reserve this many chars from right edge for "..."
+	commandMaxWidthBuffer = 5 // Reserve this many chars from right edge for "..."
+
+	// Preview pane
+	previewAutoRightMinWidth = 120 // "auto" places the preview on the right from this terminal width
+	previewRightWidthPercent = 40  // Share of the terminal width used by a right-hand preview
+	previewBottomLines       = 10  // Lines used by a bottom preview, including its border
+	previewHistoryEntries    = 3   // Past runs listed in the preview
+
+	// Argument prompt
+	argsHistoryLimit = 20 // Past argument strings offered as completions
+
+	// Typo suggestions
+	didYouMeanLimit = 3 // Close matches listed when -l finds no script
 
 	// Initial dimensions (will be overridden by terminal size)
 	initialViewportWidth  = 80
 	initialViewportHeight = 10
 )
 
-// Color palette for easy customization
-type colorPalette struct {
-	Black   string
-	Red     string
-	Green   string
-	Yellow  string
-	Blue    string
-	Magenta string
-	Cyan    string
-	White   string
-	Gray    string
-}
-
-var colors = colorPalette{
-	Black:   "#000000",
-	Red:     "#E88388",
-	Green:   "#A8CC8C",
-	Yellow:  "#DBAB79",
-	Blue:    "#71BEF2",
-	Magenta: "#D290E4",
-	Cyan:    "#66C2CD",
-	White:   "#FFFFFF",
-	Gray:    "#B9BFCA",
-}
-
+// Styles, set from the theme (see ApplyTheme)
 // https://github.com/charmbracelet/lipgloss/blob/7d1b622c64d1a68cdc94b30864ae5ec3e6abc2dd/examples/ssh/main.go#L38
 var (
-	// Styles
-	scriptNameStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color(colors.Cyan))
-
-	commandStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("250")) // Lighter gray for better readability
-
-	metadataStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")) // Darker gray for stars/run data
-
-	cursorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Magenta))
-
-	// Selected line backgrounds (Option 1: Dark Gray)
-	selectedScriptNameBgStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#2A2A2A"))
-
-	selectedCommandBgStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#2A2A2A"))
-
-	promptStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color(colors.Cyan))
-
-	defaultAnswerStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(lipgloss.Color(colors.Green))
+	scriptNameStyle    lipgloss.Style
+	commandStyle       lipgloss.Style
+	metadataStyle      lipgloss.Style // Stars, run data and hints
+	cursorStyle        lipgloss.Style
+	promptStyle        lipgloss.Style
+	defaultAnswerStyle lipgloss.Style
+	previewLabelStyle  lipgloss.Style
+	previewBorderStyle lipgloss.Style
+	groupHeaderStyle   lipgloss.Style
+	successStyle       lipgloss.Style
+	failureStyle       lipgloss.Style
+	suggestionStyle    lipgloss.Style
+
+	// Selected line backgrounds
+	selectedScriptNameBgStyle lipgloss.Style
+	selectedCommandBgStyle    lipgloss.Style
+
+	// Characters matched by the search query
+	nameMatchStyle    lipgloss.Style
+	commandMatchStyle lipgloss.Style
 )
 
 func FormatTimeAgo(t time.Time) string {
@@ -137,13 +129,14 @@ func FormatScriptOption(scored ScoredScript) string {
 
 func FormatScriptOptionWithWidth(scored ScoredScript, maxWidth int) string {
 	// Format: "[📌] script-name → command [★★★★☆ 24 runs, 2h ago]"
-	// Add pin indicator if pinned
-	pinIndicator := ""
+	// Name with the characters matched by the search highlighted, after the pin indicator
+	scriptName := highlightMatches(scored.Script.Name, scored.NameMatches, scriptNameStyle, nameMatchStyle)
 	if scored.IsPinned {
-		pinIndicator = "📌 "
+		scriptName = scriptNameStyle.Render("📌 ") + scriptName
+	}
+	if scored.Project != nil {
+		scriptName += metadataStyle.Render("  " + ShortenHome(scored.Project.Directory))
 	}
-
-	scriptName := scriptNameStyle.Render(pinIndicator + scored.Script.Name)
 
 	// Prepare metadata with source indicator
 	var metadata string
@@ -151,7 +144,7 @@ func FormatScriptOptionWithWidth(scored ScoredScript, maxWidth int) string {
 
 	// Format source indicator with color
 	if scored.Script.Source == "make" {
-		sourceIndicator = lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Green)).Render(scored.Script.Source)
+		sourceIndicator = successStyle.Render(scored.Script.Source)
 	} else if scored.Script.Source != "" {
 		sourceIndicator = metadataStyle.Render(scored.Script.Source)
 	}
@@ -176,23 +169,63 @@ func FormatScriptOptionWithWidth(scored ScoredScript, maxWidth int) string {
 
 	// Calculate available width for command (accounting for prefix, metadata, buffer)
 	commandText := scored.Script.Command
+	commandMatches := scored.CommandMatches
 	if maxWidth > 0 {
 		// Account for: "  " (2 chars) + metadata + buffer + pin indicator
 		metadataWidth := lipgloss.Width(metadata)
 		availableWidth := maxWidth - 2 - metadataWidth - commandMaxWidthBuffer
 
-		// Truncate command if needed
-		if len(commandText) > availableWidth && availableWidth > 3 {
-			commandText = commandText[:availableWidth-3] + "..."
+		// Truncate command if needed, by display width so wide and multi-byte
+		// characters are never split, and drop matches that were cut off
+		if lipgloss.Width(commandText) > availableWidth && availableWidth > 3 {
+			commandText = ansi.Truncate(commandText, availableWidth, "...")
+			kept := utf8.RuneCountInString(commandText) - len("...")
+			commandMatches = nil
+			for _, pos := range scored.CommandMatches {
+				if pos < kept {
+					commandMatches = append(commandMatches, pos)
+				}
+			}
 		}
 	}
 
-	command := commandStyle.Render(fmt.Sprintf(" %s", commandText))
+	command := commandStyle.Render(" ") +
+		highlightMatches(commandText, commandMatches, commandStyle, commandMatchStyle)
 
 	return fmt.Sprintf("%s\n  %s %s", scriptName, command, metadata)
 }
 
+// highlightMatches renders text in base style with the runes at the given positions
+// in match style, styling runs of consecutive characters together
+func highlightMatches(text string, positions []int, base, match lipgloss.Style) string {
+	if len(positions) == 0 {
+		return base.Render(text)
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && matched[i] == matched[start] {
+			continue
+		}
+		style := base
+		if matched[start] {
+			style = match
+		}
+		b.WriteString(style.Render(string(runes[start:i])))
+		start = i
+	}
+	return b.String()
+}
+
 func PromptForDefault(scored ScoredScript) (bool, error) {
+	useTerminalBackground(os.Stdout)
 	fmt.Println()
 	fmt.Println(promptStyle.Render("Run the most recent script?"))
 	fmt.Println()
@@ -210,7 +243,7 @@ func PromptForDefault(scored ScoredScript) (bool, error) {
 				Affirmative("Yes").
 				Negative("No"),
 		),
-	).WithShowHelp(false).WithShowErrors(false)
+	).WithShowHelp(false).WithShowErrors(false).WithTheme(formTheme)
 
 	err := form.Run()
 	if err != nil {
@@ -220,13 +253,180 @@ func PromptForDefault(scored ScoredScript) (bool, error) {
 	return confirmed, nil
 }
 
+// ConfirmRename asks whether history of a renamed script should be carried over
+func ConfirmRename(rename ScriptRename) (bool, error) {
+	useTerminalBackground(os.Stdout)
+	confirmed := true
+
+	description := fmt.Sprintf("Same command: %s\nCarry over %d runs", rename.Command, rename.Old.UseCount)
+	if rename.Old.IsPinned {
+		description += " and the pin"
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Was %s renamed to %s?", rename.Old.ScriptName, rename.New.Name)).
+				Description(description + "?").
+				Value(&confirmed).
+				Affirmative("Yes").
+				Negative("No"),
+		),
+	).WithShowHelp(false).WithTheme(formTheme)
+
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return false, ErrPromptCancelled
+		}
+		return false, err
+	}
+	return confirmed, nil
+}
+
+// DidYouMean lists the closest typo matches for a search that matched nothing and,
+// when stdin is a terminal, asks whether to run the best one. Returns nil if there
+// is nothing to run.
+func DidYouMean(query string, matches []ScoredScript) (*ScoredScript, error) {
+	fmt.Printf("No scripts matching '%s' found. Did you mean:\n", query)
+	for _, scored := range matches[:min(len(matches), didYouMeanLimit)] {
+		fmt.Printf("  %s → %s\n", scored.Script.Name, scored.Script.Command)
+	}
+
+	if !stdinIsTerminal() {
+		return nil, nil
+	}
+	useTerminalBackground(os.Stdout)
+
+	best := matches[0]
+	run := true
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Run %s?", best.Script.Name)).
+				Value(&run).
+				Affirmative("Yes").
+				Negative("No"),
+		),
+	).WithShowHelp(false).WithTheme(formTheme)
+
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, ErrPromptCancelled
+		}
+		return nil, err
+	}
+	if !run {
+		return nil, nil
+	}
+	return &best, nil
+}
+
+// stdinIsTerminal reports whether prompts can be answered, i.e. input isn't piped
+func stdinIsTerminal() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+// ErrPromptCancelled is returned when the user cancels a prompt with ctrl+c
+var ErrPromptCancelled = errors.New("prompt cancelled")
+
+// PromptForMakeVariables shows a form with one input per Make variable, pre-filled with the
+// remembered value or the Makefile default. Returns the entered values, including empty ones.
+func PromptForMakeVariables(target string, variables []MakeVariable, remembered map[string]string) (map[string]string, error) {
+	useTerminalBackground(os.Stdout)
+	values := make([]string, len(variables))
+	fields := make([]huh.Field, len(variables))
+
+	for i, variable := range variables {
+		values[i] = variable.Default
+		if value, ok := remembered[variable.Name]; ok {
+			values[i] = value
+		}
+
+		description := "not set in the Makefile"
+		if variable.Default != "" {
+			description = "default: " + variable.Default
+		}
+
+		fields[i] = huh.NewInput().
+			Title(variable.Name).
+			Description(description).
+			Value(&values[i])
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(fields...).
+			Title(fmt.Sprintf("make %s", target)).
+			Description("Leave a value empty to use the Makefile's own"),
+	).WithTheme(formTheme)
+
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, ErrPromptCancelled
+		}
+		return nil, err
+	}
+
+	result := make(map[string]string, len(variables))
+	for i, variable := range variables {
+		result[variable.Name] = strings.TrimSpace(values[i])
+	}
+	return result, nil
+}
+
+// MakeOverrides picks the values to pass to make for a target's variables. Empty values
+// are dropped so make falls back to its own defaults, as are variables no longer in the recipe.
+func MakeOverrides(variables []MakeVariable, values map[string]string) map[string]string {
+	overrides := make(map[string]string, len(variables))
+	for _, variable := range variables {
+		if value := values[variable.Name]; value != "" {
+			overrides[variable.Name] = value
+		}
+	}
+	return overrides
+}
+
 func ShowScriptSelection(scoredScripts []ScoredScript, initialFilter string) (*ScoredScript, error) {
 	// Use the custom filterable selector for all cases now (provides dynamic sizing)
-	return ShowScriptSelectionWithFilter(scoredScripts, initialFilter, nil, "")
+	result, err := ShowScriptSelectionWithFilter(SelectorParams{
+		Scripts:       scoredScripts,
+		InitialFilter: initialFilter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Script, nil
 }
 
 func ShowScriptSelectionWithDB(scoredScripts []ScoredScript, initialFilter string, db *Database, directory string) (*ScoredScript, error) {
-	return ShowScriptSelectionWithFilter(scoredScripts, initialFilter, db, directory)
+	result, err := ShowScriptSelectionWithFilter(SelectorParams{
+		Scripts:       scoredScripts,
+		InitialFilter: initialFilter,
+		DB:            db,
+		Directory:     directory,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Script, nil
+}
+
+// SelectorParams configures the interactive script selector
+type SelectorParams struct {
+	Scripts       []ScoredScript   // Scripts to choose from, already sorted by frecency
+	InitialFilter string           // Pre-populated filter text
+	DB            *Database        // Optional: enables toggling pins and run history in the preview
+	Directory     string           // History key (see ResolveProject) used for pins and run history, unless a script has a Project
+	Config        *Config          // Optional: user preferences (defaults if nil)
+	Graph         *DependencyGraph // Optional: enables the execution chain line
+	Args          []string         // Arguments given after --, used as the default in the args prompt
+	Suggestion    *Suggestion      // Optional: predicted next script, listed first while the filter is empty
+	Keys          *KeyMap          // Optional: key bindings (see KeyMapFromConfig; defaults if nil)
+}
+
+// SelectorResult is what the user picked in the selector
+type SelectorResult struct {
+	Script *ScoredScript // nil if the user quit without selecting
+	Args   []string      // Arguments to run the script with (edited via tab, or SelectorParams.Args)
 }
 
 func PrintScriptsList(scoredScripts []ScoredScript, packageManager string) {
@@ -254,8 +454,36 @@ type filterableSelector struct {
 	quitting        bool
 	db              *Database
 	directory       string
+	config          *Config
+	graph           *DependencyGraph
+	collapsed       map[string]bool // Lifecycle hook groups collapsed under their main script
+	depths          []int           // Hook nesting depth of each entry in filteredScripts
+	groupBy         string          // Section headers: GroupNone, GroupSource, GroupWorkspace or GroupPrefix
+	groups          []string        // Section of each entry in filteredScripts ("" for none)
+	groupSizes      map[string]int  // Scripts in each section, including those collapsed away
+	collapsedGroups map[string]bool // Sections collapsed to their first script, per grouping mode
+	showPreview     bool
+	recentRuns      map[string][]ScriptRun // Run history per script, loaded lazily for the preview
+	argsInput       textinput.Model        // Argument prompt opened with tab
+	editingArgs     bool
+	argsError       string   // Parse error shown under the argument prompt
+	resultArgs      []string // Arguments returned with the result
+	suggestion      *Suggestion
+	suggested       bool           // Whether filteredScripts starts with the suggestion
+	inputKeys       KeyMap         // Bindings while typing into the filter
+	normalKeys      KeyMap         // Bindings in vim normal mode
+	typing          bool           // Keys go to the filter (always, unless vimMode is on)
+	help            help.Model     // Help line generated from the active bindings
+	inline          SelectorHeight // Drawn below the prompt instead of full screen, if set
 }
 
+// Preview pane placements
+const (
+	previewHidden = ""
+	previewRight  = "right"
+	previewBottom = "bottom"
+)
+
 // Init initializes the filterableSelector model
 func (m *filterableSelector) Init() tea.Cmd {
 	return textinput.Blink
@@ -267,58 +495,94 @@ func (m *filterableSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
+		if m.editingArgs {
+			return m.updateArgsPrompt(msg)
+		}
+
+		keys := m.activeKeys()
+		switch {
+		case key.Matches(msg, keys.Quit):
 			m.quitting = true
 			return m, tea.Quit
 
-		case "enter":
+		case key.Matches(msg, keys.Select):
 			if len(m.filteredScripts) > 0 && m.selected < len(m.filteredScripts) {
 				m.result = &m.filteredScripts[m.selected]
 				m.quitting = true
 				return m, tea.Quit
 			}
 
-		case "alt+p":
+		case key.Matches(msg, keys.TogglePin):
 			// Toggle pin for the currently selected script
 			if len(m.filteredScripts) > 0 && m.selected < len(m.filteredScripts) && m.db != nil {
 				selectedScript := &m.filteredScripts[m.selected]
-				isPinned, err := m.db.TogglePin(m.directory, selectedScript.Script.Name, selectedScript.Script.Source)
+				isPinned, err := m.db.TogglePin(m.historyKey(*selectedScript), selectedScript.Script.Name, selectedScript.Script.Source)
 				if err == nil {
 					// Update the IsPinned status in the current script
 					selectedScript.IsPinned = isPinned
 
-					// Update in allScripts array (match both name and source)
+					// Update in allScripts array (match name, source and project)
 					for i := range m.allScripts {
-						if m.allScripts[i].Script.Name == selectedScript.Script.Name &&
-							m.allScripts[i].Script.Source == selectedScript.Script.Source {
+						if scriptKey(m.allScripts[i]) == scriptKey(*selectedScript) {
 							m.allScripts[i].IsPinned = isPinned
 							break
 						}
 					}
+					if m.suggestion != nil && scriptKey(m.suggestion.Script) == scriptKey(*selectedScript) {
+						m.suggestion.Script.IsPinned = isPinned
+					}
 
 					// Re-sort scripts to move pinned items to top
-					m.filteredScripts = m.allScripts
-					if m.filter.Value() != "" {
-						m.filterScripts()
-					}
+					m.filterScripts()
 				}
 			}
 
-		case "esc", "ctrl+u", "alt+backspace", "ctrl+backspace", "ctrl+w":
-			// Clear filter and show all scripts
-			// Multiple shortcuts for different terminal/platform preferences:
-			// - esc: universal
-			// - ctrl+u: standard terminal "clear line"
-			// - alt+backspace: may work as cmd+backspace on Mac
-			// - ctrl+backspace: works on some terminals
-			// - ctrl+w: standard "delete word backward"
+		case key.Matches(msg, keys.EditArgs):
+			// Edit the arguments before running the selected script
+			if len(m.filteredScripts) > 0 && m.selected < len(m.filteredScripts) {
+				return m, m.openArgsPrompt()
+			}
+
+		case key.Matches(msg, keys.Preview):
+			// Show/hide the preview pane
+			m.showPreview = !m.showPreview
+			m.layout()
+
+		case key.Matches(msg, keys.ToggleHooks):
+			// Collapse/expand the lifecycle hooks grouped under the selected script
+			if len(m.filteredScripts) > 0 && m.selected < len(m.filteredScripts) {
+				m.toggleHooks()
+			}
+
+		case key.Matches(msg, keys.Group):
+			// Cycle the section headers: none → source → workspace → prefix
+			m.cycleGrouping()
+
+		case key.Matches(msg, keys.CollapseGroup):
+			// Collapse/expand the section of the selected script
+			if len(m.filteredScripts) > 0 && m.selected < len(m.filteredScripts) {
+				m.toggleGroup()
+			}
+
+		case key.Matches(msg, keys.NormalMode):
+			// Vim mode: stop typing, keeping the filter
+			m.setTyping(false)
+
+		case key.Matches(msg, keys.InsertMode):
+			return m, m.setTyping(true)
+
+		case key.Matches(msg, keys.ClearFilter):
+			// Clear filter and show all scripts, or quit if it's already empty
+			if m.filter.Value() == "" {
+				m.quitting = true
+				return m, tea.Quit
+			}
 			m.filter.SetValue("")
-			m.filteredScripts = m.allScripts
+			m.filterScripts()
 			m.selected = 0
 			m.viewport.GotoTop()
 
-		case "up":
+		case key.Matches(msg, keys.Up):
 			if m.selected > 0 {
 				m.selected--
 			} else {
@@ -327,7 +591,7 @@ func (m *filterableSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.updateViewport()
 
-		case "down":
+		case key.Matches(msg, keys.Down):
 			if m.selected < len(m.filteredScripts)-1 {
 				m.selected++
 			} else {
@@ -336,7 +600,7 @@ func (m *filterableSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.updateViewport()
 
-		default:
+		case m.typing:
 			// Update the text input (handles typing, backspace, etc.)
 			m.filter, cmd = m.filter.Update(msg)
 			// Update filtered list based on new filter value
@@ -352,37 +616,180 @@ func (m *filterableSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		if m.inline.Inline() {
+			m.height = m.inline.lines(msg.Height)
+		}
+		m.layout()
+	}
+
+	return m, cmd
+}
+
+// activeKeys returns the bindings of the current mode: typing into the filter, or
+// vim normal mode
+func (m *filterableSelector) activeKeys() KeyMap {
+	if m.typing {
+		return m.inputKeys
+	}
+	return m.normalKeys
+}
 
-		// Update text input width to match terminal (leave space for prompt)
-		m.filter.Width = msg.Width - filterPromptWidth
+// setTyping switches between typing into the filter and vim normal mode
+func (m *filterableSelector) setTyping(typing bool) tea.Cmd {
+	m.typing = typing
+	if typing {
+		return m.filter.Focus()
+	}
+	m.filter.Blur()
+	return nil
+}
 
-		// Calculate viewport dimensions using configured constants
-		viewportHeight := max(msg.Height-headerFooterLines, minViewportHeight)
+// openArgsPrompt opens the argument input for the selected script, pre-filled with
+// the args given on the command line or, failing that, the args it was last run with
+func (m *filterableSelector) openArgsPrompt() tea.Cmd {
+	selected := m.filteredScripts[m.selected]
 
-		// Apply max height if configured (0 = no limit)
-		if maxViewportHeight > 0 && viewportHeight > maxViewportHeight {
-			viewportHeight = maxViewportHeight
+	value := JoinArgs(m.resultArgs)
+	if len(m.resultArgs) == 0 {
+		if runs := m.runsFor(selected); len(runs) > 0 {
+			value = runs[0].Args
 		}
+	}
 
-		m.viewport.Width = msg.Width
-		m.viewport.Height = viewportHeight
+	var history []string
+	if m.db != nil {
+		history, _ = m.db.GetArgsHistory(m.historyKey(selected), selected.Script.Name, selected.Script.Source, argsHistoryLimit)
 	}
 
+	ti := textinput.New()
+	ti.Prompt = selected.Script.Name + " "
+	ti.PromptStyle = scriptNameStyle
+	ti.Placeholder = "arguments, e.g. --watch"
+	ti.CharLimit = 0
+	ti.Width = max(m.width-lipgloss.Width(ti.Prompt)-1, 0)
+	ti.ShowSuggestions = true
+	ti.SetSuggestions(history)
+	ti.SetValue(value)
+	ti.CursorEnd()
+
+	m.argsInput = ti
+	m.editingArgs = true
+	m.argsError = ""
+	return m.argsInput.Focus()
+}
+
+// updateArgsPrompt handles keys while the argument input is open
+func (m *filterableSelector) updateArgsPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc":
+		// Back to the list without running
+		m.editingArgs = false
+		m.argsError = ""
+		return m, nil
+
+	case "enter":
+		args, err := SplitArgs(m.argsInput.Value())
+		if err != nil {
+			m.argsError = err.Error()
+			return m, nil
+		}
+		m.result = &m.filteredScripts[m.selected]
+		m.resultArgs = args
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.argsInput, cmd = m.argsInput.Update(msg)
+	m.argsError = ""
 	return m, cmd
 }
 
+// layout sizes the filter and viewport for the terminal size and preview placement
+func (m *filterableSelector) layout() {
+	if m.width == 0 {
+		return // No WindowSizeMsg yet
+	}
+
+	// Update text input width to match terminal (leave space for prompt)
+	m.filter.Width = m.width - filterPromptWidth
+
+	// Calculate viewport dimensions using configured constants
+	reservedLines := headerFooterLines
+	if m.graph != nil {
+		reservedLines += chainPreviewLines
+	}
+	if m.previewPlacement() == previewBottom {
+		reservedLines += previewBottomLines
+	}
+	viewportHeight := max(m.height-reservedLines, minViewportHeight)
+
+	// Apply max height if configured (0 = no limit)
+	if maxViewportHeight > 0 && viewportHeight > maxViewportHeight {
+		viewportHeight = maxViewportHeight
+	}
+
+	m.viewport.Width = m.listWidth()
+	m.viewport.Height = viewportHeight
+}
+
+// previewPlacement returns where the preview pane is drawn, or previewHidden
+func (m *filterableSelector) previewPlacement() string {
+	if !m.showPreview {
+		return previewHidden
+	}
+
+	// A short inline selector has no room for the preview below the list
+	if m.inline.Inline() && m.height < minInlineHeight+previewBottomLines {
+		return previewRight
+	}
+
+	switch m.config.PreviewPosition {
+	case previewRight, previewBottom:
+		return m.config.PreviewPosition
+	default:
+		if m.width >= previewAutoRightMinWidth {
+			return previewRight
+		}
+		return previewBottom
+	}
+}
+
+// listWidth returns the width available to the script list
+func (m *filterableSelector) listWidth() int {
+	if m.previewPlacement() == previewRight {
+		return m.width - m.width*previewRightWidthPercent/100
+	}
+	return m.width
+}
+
 // updateViewport scrolls to keep the selected item visible
 func (m *filterableSelector) updateViewport() {
-	selectedLine := m.selected * linesPerScriptOption
+	selectedLine := m.entryLine(m.selected)
 	selectedLastLine := selectedLine + linesPerScriptOption - 1 // Last line of selected item
 
+	// Show the section header along with the first script of a section
+	selectedTop := selectedLine
+	if m.hasHeader(m.selected) {
+		selectedTop--
+	}
+
+	// Don't leave blank lines at the bottom after the list got shorter (a section collapsed)
+	if maxOffset := max(0, m.entryLine(len(m.filteredScripts))-m.viewport.Height); m.viewport.YOffset > maxOffset {
+		m.viewport.YOffset = maxOffset
+	}
+
 	// Get viewport bounds
 	viewportTop := m.viewport.YOffset
 	viewportBottom := m.viewport.YOffset + m.viewport.Height - 1 // Last visible line (inclusive)
 
 	// Scroll up if selection starts above viewport
-	if selectedLine < viewportTop {
-		m.viewport.YOffset = selectedLine
+	if selectedTop < viewportTop {
+		m.viewport.YOffset = selectedTop
 	}
 
 	// Scroll down if selection extends to or beyond viewport bottom
@@ -393,22 +800,389 @@ func (m *filterableSelector) updateViewport() {
 	}
 }
 
+// entryLine returns the first line of entry i in the list, counting the section
+// headers drawn above it
+func (m *filterableSelector) entryLine(i int) int {
+	line := i * linesPerScriptOption
+	for j := 0; j <= i && j < len(m.groups); j++ {
+		if m.hasHeader(j) {
+			line++
+		}
+	}
+	return line
+}
+
+// hasHeader reports whether a section header is drawn above entry i
+func (m *filterableSelector) hasHeader(i int) bool {
+	return i < len(m.groups) && m.groups[i] != "" && (i == 0 || m.groups[i-1] != m.groups[i])
+}
+
 // filterScripts updates the filtered list based on the current filter value
 func (m *filterableSelector) filterScripts() {
 	filterValue := strings.TrimSpace(m.filter.Value())
 
+	m.suggested = false
+	m.groups = nil
 	if filterValue == "" {
-		m.filteredScripts = m.allScripts
+		// Unfiltered list: nest lifecycle hooks under their main script
+		m.filteredScripts, m.depths = m.groupHooks(m.allScripts)
+		if m.grouped() {
+			m.filteredScripts, m.depths, m.groups = groupScripts(m.filteredScripts, m.depths, m.groupBy)
+			m.collapseGroups()
+		}
+
+		// The suggested next script goes on top (it also keeps its usual place)
+		if m.suggestion != nil {
+			m.filteredScripts = append([]ScoredScript{m.suggestion.Script}, m.filteredScripts...)
+			m.depths = append([]int{0}, m.depths...)
+			if m.groups != nil {
+				m.groups = append([]string{""}, m.groups...)
+			}
+			m.suggested = true
+		}
 		return
 	}
 
 	// Use the fuzzy SearchScripts function for consistent behavior
+	// Search results stay flat so matching hooks are never hidden
 	m.filteredScripts = SearchScripts(m.allScripts, filterValue)
+	m.depths = nil
+}
+
+// grouped reports whether the unfiltered list is split into sections
+func (m *filterableSelector) grouped() bool {
+	return normalizeGroupMode(m.groupBy) != GroupNone
+}
+
+// collapseGroups counts the scripts of each section, then drops all but the first
+// entry of collapsed sections
+func (m *filterableSelector) collapseGroups() {
+	m.groupSizes = make(map[string]int)
+	for _, group := range m.groups {
+		m.groupSizes[group]++
+	}
+
+	scripts := m.filteredScripts[:0]
+	depths := m.depths[:0]
+	groups := m.groups[:0]
+	for i, group := range m.groups {
+		if m.collapsedGroups[m.groupBy+"/"+group] && i > 0 && m.groups[i-1] == group {
+			continue
+		}
+		scripts = append(scripts, m.filteredScripts[i])
+		depths = append(depths, m.depths[i])
+		groups = append(groups, group)
+	}
+	m.filteredScripts, m.depths, m.groups = scripts, depths, groups
+}
+
+// cycleGrouping switches to the next grouping mode, keeping the selected script
+func (m *filterableSelector) cycleGrouping() {
+	selectedKey := ""
+	if m.selected < len(m.filteredScripts) {
+		selectedKey = scriptKey(m.filteredScripts[m.selected])
+	}
+
+	m.groupBy = nextGroupMode(m.groupBy, m.allScripts)
+	m.filterScripts()
+
+	m.selected = 0
+	for i, scored := range m.filteredScripts {
+		if scriptKey(scored) == selectedKey && !(i == 0 && m.suggested) {
+			m.selected = i
+			break
+		}
+	}
+	m.updateViewport()
+}
+
+// toggleGroup collapses or expands the section of the selected script
+func (m *filterableSelector) toggleGroup() {
+	if m.selected >= len(m.groups) || m.groups[m.selected] == "" {
+		return // Not grouped, or the suggestion above the sections
+	}
+
+	group := m.groups[m.selected]
+	key := m.groupBy + "/" + group
+	m.collapsedGroups[key] = !m.collapsedGroups[key]
+	m.filterScripts()
+
+	// Keep the selection on the section's first script
+	for i := range m.groups {
+		if m.groups[i] == group {
+			m.selected = i
+			break
+		}
+	}
+	m.updateViewport()
+}
+
+// hookParentKey returns the key of the main script a hook is grouped under
+func hookParentKey(scored ScoredScript) string {
+	return projectScopedKey(scored, scored.Script.HookOf)
+}
+
+// scriptKey identifies a script across sources (same convention as ScoreScripts)
+func scriptKey(scored ScoredScript) string {
+	return projectScopedKey(scored, scored.Script.Name)
+}
+
+// projectScopedKey is "name:source", followed by "@project" in --global mode where
+// several projects have a "dev"
+func projectScopedKey(scored ScoredScript, name string) string {
+	key := name + ":" + scored.Script.Source
+	if scored.Project != nil {
+		key += "@" + scored.Project.Key
+	}
+	return key
+}
+
+// historyKey is the history key pins, runs and arguments of a script are stored under
+func (m *filterableSelector) historyKey(scored ScoredScript) string {
+	if scored.Project != nil {
+		return scored.Project.Key
+	}
+	return m.directory
+}
+
+// groupHooks orders scripts so pre/post hooks follow their main script,
+// omitting hooks of collapsed groups. Returns the nesting depth of each entry.
+func (m *filterableSelector) groupHooks(scripts []ScoredScript) ([]ScoredScript, []int) {
+	present := make(map[string]bool, len(scripts))
+	for _, scored := range scripts {
+		present[scriptKey(scored)] = true
+	}
+
+	children := make(map[string][]ScoredScript)
+	for _, scored := range scripts {
+		if scored.Script.HookOf != "" && present[hookParentKey(scored)] {
+			parentKey := hookParentKey(scored)
+			children[parentKey] = append(children[parentKey], scored)
+		}
+	}
+	for _, hooks := range children {
+		// pre hooks run before the main script, so list them first
+		sort.SliceStable(hooks, func(i, j int) bool {
+			return strings.HasPrefix(hooks[i].Script.Name, "pre") && !strings.HasPrefix(hooks[j].Script.Name, "pre")
+		})
+	}
+
+	grouped := make([]ScoredScript, 0, len(scripts))
+	depths := make([]int, 0, len(scripts))
+	var add func(scored ScoredScript, depth int)
+	add = func(scored ScoredScript, depth int) {
+		grouped = append(grouped, scored)
+		depths = append(depths, depth)
+		if m.collapsed[scriptKey(scored)] {
+			return
+		}
+		for _, hook := range children[scriptKey(scored)] {
+			add(hook, depth+1)
+		}
+	}
+
+	for _, scored := range scripts {
+		if scored.Script.HookOf != "" && present[hookParentKey(scored)] {
+			continue // Added under its main script
+		}
+		add(scored, 0)
+	}
+
+	return grouped, depths
+}
+
+// hookCount returns how many hooks are grouped under a script
+func (m *filterableSelector) hookCount(scored ScoredScript) int {
+	count := 0
+	for _, other := range m.allScripts {
+		if other.Script.HookOf == scored.Script.Name && other.Script.Source == scored.Script.Source {
+			count++
+		}
+	}
+	return count
+}
+
+// toggleHooks collapses or expands the hook group of the selected script
+func (m *filterableSelector) toggleHooks() {
+	selected := m.filteredScripts[m.selected]
+
+	// On a hook, toggle its main script's group instead
+	groupKey := scriptKey(selected)
+	if selected.Script.HookOf != "" {
+		groupKey = hookParentKey(selected)
+	}
+
+	m.collapsed[groupKey] = !m.collapsed[groupKey]
+	m.filterScripts()
+
+	// Keep the selection on the group's main script
+	for i, scored := range m.filteredScripts {
+		if scriptKey(scored) == groupKey {
+			m.selected = i
+			break
+		}
+	}
+	m.updateViewport()
+}
+
+// executionChain describes what runs when the selected script runs, e.g. "prebuild → build → postbuild"
+func (m *filterableSelector) executionChain() string {
+	if m.graph == nil || len(m.filteredScripts) == 0 || m.selected >= len(m.filteredScripts) {
+		return ""
+	}
+
+	selected := m.filteredScripts[m.selected]
+	chain := m.graph.ExecutionChain(m.graph.Node(selected.Script.Name, selected.Script.Source))
+	if len(chain) < 2 {
+		return ""
+	}
+
+	names := make([]string, len(chain))
+	for i, node := range chain {
+		names[i] = node.Name
+	}
+	return strings.Join(names, " → ")
+}
+
+// runsFor returns the recent runs of a script, querying the database once per script
+func (m *filterableSelector) runsFor(scored ScoredScript) []ScriptRun {
+	if m.db == nil {
+		return nil
+	}
+
+	key := scriptKey(scored)
+	if runs, ok := m.recentRuns[key]; ok {
+		return runs
+	}
+
+	runs, err := m.db.GetRecentRuns(m.historyKey(scored), scored.Script.Name, scored.Script.Source, previewHistoryEntries)
+	if err != nil {
+		runs = nil
+	}
+	m.recentRuns[key] = runs
+	return runs
+}
+
+// FormatDuration formats a run duration compactly, e.g. "850ms", "4.2s", "3m12s"
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+// formatRunStatus renders "✓ exit 0" or "✗ exit 2" with color
+func formatRunStatus(run ScriptRun) string {
+	if run.ExitCode == 0 {
+		return successStyle.Render("✓ exit 0")
+	}
+	if run.ExitCode < 0 {
+		return failureStyle.Render("✗ interrupted")
+	}
+	return failureStyle.Render(fmt.Sprintf("✗ exit %d", run.ExitCode))
+}
+
+// renderPreview builds the preview pane content for the selected script
+func (m *filterableSelector) renderPreview(width int) string {
+	if len(m.filteredScripts) == 0 || m.selected >= len(m.filteredScripts) {
+		return metadataStyle.Render("No script selected")
+	}
+
+	selected := m.filteredScripts[m.selected]
+	script := selected.Script
+	wrap := lipgloss.NewStyle().Width(width)
+
+	var lines []string
+	lines = append(lines, scriptNameStyle.Render(script.Name)+" "+metadataStyle.Render("["+script.Source+"]"))
+
+	if script.Description != "" {
+		lines = append(lines, wrap.Render(script.Description))
+	}
+	if script.File != "" {
+		location := script.File
+		if selected.Project != nil {
+			location = filepath.Join(ShortenHome(selected.Project.Directory), script.File)
+		}
+		if script.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, script.Line)
+		}
+		lines = append(lines, metadataStyle.Render(location))
+	}
+
+	lines = append(lines, "", previewLabelStyle.Render("Command"))
+	command := script.Command
+	if command == "" {
+		command = "(no recipe)"
+	}
+	lines = append(lines, commandStyle.Render(wrap.Render(command)))
+
+	// Makefile variables offered as parameters before running
+	if variables := ResolveMakeVariables(script.Variables); len(variables) > 0 {
+		params := make([]string, len(variables))
+		for i, variable := range variables {
+			params[i] = variable.Name
+			if variable.Default != "" {
+				params[i] += "=" + variable.Default
+			}
+		}
+		lines = append(lines, "", previewLabelStyle.Render("Parameters"))
+		lines = append(lines, wrap.Render(strings.Join(params, " ")))
+	}
+
+	// Dependencies from the script graph
+	if m.graph != nil {
+		if node := m.graph.Node(script.Name, script.Source); node != nil && len(node.Edges) > 0 {
+			deps := make([]string, len(node.Edges))
+			for i, edge := range node.Edges {
+				deps[i] = fmt.Sprintf("%s (%s)", edge.To.Name, edge.Kind)
+			}
+			lines = append(lines, "", previewLabelStyle.Render("Dependencies"))
+			lines = append(lines, wrap.Render(strings.Join(deps, ", ")))
+			if chain := m.executionChain(); chain != "" {
+				lines = append(lines, metadataStyle.Render(wrap.Render("⛓ "+chain)))
+			}
+		}
+	}
+
+	// Run history
+	if runs := m.runsFor(selected); len(runs) > 0 {
+		last := runs[0]
+		lines = append(lines, "", previewLabelStyle.Render("Last run"))
+		lines = append(lines, fmt.Sprintf("%s %s", formatRunStatus(last),
+			metadataStyle.Render(fmt.Sprintf("· %s · %s", FormatDuration(last.Duration), FormatTimeAgo(last.StartedAt)))))
+
+		lines = append(lines, "", previewLabelStyle.Render("History"))
+		for _, run := range runs {
+			entry := fmt.Sprintf("%s · %s", FormatTimeAgo(run.StartedAt), FormatDuration(run.Duration))
+			if run.Args != "" {
+				entry += " · " + run.Args
+			}
+			status := successStyle.Render("✓")
+			if run.ExitCode != 0 {
+				status = failureStyle.Render("✗")
+			}
+			lines = append(lines, status+" "+metadataStyle.Render(entry))
+		}
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 // View renders the UI
 func (m *filterableSelector) View() string {
 	if m.quitting {
+		// Inline, the chosen script stays on screen in place of the selector
+		if m.inline.Inline() && m.result != nil {
+			echo := promptStyle.Render("📦 ") + scriptNameStyle.Render(m.result.Script.Name)
+			if len(m.resultArgs) > 0 {
+				echo += " " + commandStyle.Render(JoinArgs(m.resultArgs))
+			}
+			return echo + "\n"
+		}
 		return ""
 	}
 
@@ -417,45 +1191,96 @@ func (m *filterableSelector) View() string {
 	// Title with optional debug info
 	title := promptStyle.Render("📦 Search scripts (type to filter)")
 	if debugMode {
-		selectedLine := m.selected * linesPerScriptOption
+		selectedLine := m.entryLine(m.selected)
 		debugInfo := metadataStyle.Render(fmt.Sprintf(" [Term: %dx%d, VP: %dx%d (offset:%d), Sel: %d (line:%d), Scripts: %d]",
 			m.width, m.height, m.viewport.Width, m.viewport.Height, m.viewport.YOffset, m.selected, selectedLine, len(m.filteredScripts)))
 		title += debugInfo
 	}
+	if !m.typing {
+		title += metadataStyle.Render(" · normal mode")
+	}
+	if m.grouped() {
+		title += metadataStyle.Render(" · grouped by " + m.groupBy)
+	}
 	s.WriteString(title + "\n\n")
 
-	// Filter input
-	s.WriteString(m.filter.View() + "\n\n")
+	// Filter input, replaced by the argument prompt while editing args
+	if m.editingArgs {
+		argsLine := m.argsInput.View()
+		if m.argsError != "" {
+			argsLine += " " + failureStyle.Render(m.argsError)
+		}
+		s.WriteString(argsLine + "\n\n")
+	} else {
+		// The blank line below the filter notes when results are only near misses
+		hint := ""
+		if len(m.filteredScripts) > 0 && m.filteredScripts[0].Typo {
+			hint = metadataStyle.Render("No exact matches. Did you mean:")
+		}
+		s.WriteString(m.filter.View() + "\n" + hint + "\n")
+	}
 
 	// Build options view
 	var optionsView strings.Builder
+	listWidth := m.listWidth()
 	cursor := cursorStyle.Render("❯ ")
 	blank := strings.Repeat(" ", lipgloss.Width(cursor))
 
 	if len(m.filteredScripts) == 0 {
 		optionsView.WriteString(metadataStyle.Render("No matching scripts found") + "\n")
 	} else {
+
 		for i, scored := range m.filteredScripts {
+			// Section header above the first script of each section
+			if m.hasHeader(i) {
+				group := m.groups[i]
+				marker := "▾ "
+				if m.collapsedGroups[m.groupBy+"/"+group] {
+					marker = "▸ "
+				}
+				header := groupHeaderStyle.Render(marker+group) + metadataStyle.Render(fmt.Sprintf(" (%d)", m.groupSizes[group]))
+				optionsView.WriteString(lipgloss.NewStyle().MaxWidth(listWidth).Render(header) + "\n")
+			}
+
 			// Add cursor for selected item
 			prefix := blank
 			if i == m.selected {
 				prefix = cursor
 			}
 
+			// Indent hooks nested under their main script
+			depth := 0
+			if i < len(m.depths) {
+				depth = m.depths[i]
+			}
+			indent := strings.Repeat("  ", depth) // Aligns the command line under the hook's name
+
 			// Format the option with width constraint to prevent wrapping
-			formatted := FormatScriptOptionWithWidth(scored, m.width)
+			formatted := FormatScriptOptionWithWidth(scored, listWidth-len(indent))
 
 			// Add prefix to the first line (script name)
 			lines := strings.Split(formatted, "\n")
 			if len(lines) > 0 {
 				scriptNameLine := lines[0]
+				if depth > 0 {
+					scriptNameLine = strings.Repeat("  ", depth-1) + metadataStyle.Render("↳ ") + scriptNameLine
+				}
+				if i == 0 && m.suggested {
+					scriptNameLine += suggestionStyle.Render(" ✨ suggested next · " + m.suggestion.Reason)
+				}
+				if m.collapsed[scriptKey(scored)] && m.depths != nil {
+					if hooks := m.hookCount(scored); hooks > 0 {
+						scriptNameLine += metadataStyle.Render(fmt.Sprintf(" ▸ +%d hooks", hooks))
+					}
+				}
 				// Apply full-width background to selected item's script name line
 				if i == m.selected {
-					scriptNameLine = selectedScriptNameBgStyle.Width(m.width).Render(scriptNameLine)
+					scriptNameLine = selectedScriptNameBgStyle.Width(listWidth).Render(scriptNameLine)
 				}
 				optionsView.WriteString(prefix + scriptNameLine + "\n")
 				// Add remaining lines with proper indentation (command + metadata)
 				for _, line := range lines[1:] {
+					line = indent + line
 					// Apply background to selected item's command line (starting after indentation)
 					if i == m.selected {
 						// Find where actual content starts (after leading spaces)
@@ -464,7 +1289,7 @@ func (m *filterableSelector) View() string {
 						indent := line[:leadingSpaces]
 						content := line[leadingSpaces:]
 						// Apply background from first letter to end of line
-						line = indent + selectedCommandBgStyle.Width(m.width-leadingSpaces).Render(content)
+						line = indent + selectedCommandBgStyle.Width(listWidth-leadingSpaces).Render(content)
 					}
 					optionsView.WriteString(line + "\n")
 				}
@@ -481,29 +1306,141 @@ func (m *filterableSelector) View() string {
 	// Ensure viewport is scrolled to show selected item
 	m.updateViewport()
 
-	// Render viewport
-	s.WriteString(m.viewport.View())
+	// Render viewport, with the preview pane beside or below it
+	switch m.previewPlacement() {
+	case previewRight:
+		previewWidth := m.width - listWidth - 3 // Border and padding
+		preview := previewBorderStyle.
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			PaddingLeft(1).
+			Height(m.viewport.Height).
+			MaxHeight(m.viewport.Height).
+			Render(m.renderPreview(previewWidth))
+		s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.viewport.View(), preview))
+	case previewBottom:
+		s.WriteString(m.viewport.View() + "\n")
+		preview := previewBorderStyle.
+			Border(lipgloss.NormalBorder(), true, false, false, false).
+			Width(m.width).
+			Height(previewBottomLines - 1).
+			MaxHeight(previewBottomLines).
+			Render(m.renderPreview(m.width))
+		s.WriteString(preview)
+	default:
+		s.WriteString(m.viewport.View())
+	}
 
 	// Optional line count debug
 	if debugMode {
 		actualLines := strings.Count(content, "\n")
-		lineDebug := metadataStyle.Render(fmt.Sprintf("\nContent lines: %d, Expected: %d", actualLines, len(m.filteredScripts)*linesPerScriptOption))
+		lineDebug := metadataStyle.Render(fmt.Sprintf("\nContent lines: %d, Expected: %d", actualLines, m.entryLine(len(m.filteredScripts))))
 		s.WriteString(lineDebug)
 	}
 
+	// Execution chain of the selected script (pre/post hooks, nested runs)
+	if m.graph != nil {
+		chainLine := ""
+		if chain := m.executionChain(); chain != "" {
+			chainLine = metadataStyle.Render("⛓ " + chain)
+		}
+		s.WriteString("\n" + chainLine)
+	}
+
 	// Help text
-	help := metadataStyle.Render("\n↑/↓: navigate • enter: select • alt-p: toggle pin • esc: clear • q: quit")
+	m.help.Width = m.width
+	help := "\n" + m.help.View(m.activeKeys())
+	if m.editingArgs {
+		help = metadataStyle.Render("\nenter: run • tab: complete • ↑/↓: previous args • esc: back to list")
+	}
 	s.WriteString(help)
 
 	return s.String()
 }
 
+// SelectorHeight is how much of the terminal an inline selector takes: a number of
+// lines or a share of the terminal height. The zero value means full screen.
+type SelectorHeight struct {
+	Lines   int
+	Percent int
+}
+
+// ParseSelectorHeight parses a height like "15" (lines) or "40%". "" and "full" mean
+// full screen.
+func ParseSelectorHeight(value string) (SelectorHeight, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "full" {
+		return SelectorHeight{}, nil
+	}
+
+	number, isPercent := strings.CutSuffix(value, "%")
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 || (isPercent && n > 100) {
+		return SelectorHeight{}, fmt.Errorf("invalid height '%s' (use a number of lines, a percentage like 40%%, or full)", value)
+	}
+	if isPercent {
+		return SelectorHeight{Percent: n}, nil
+	}
+	return SelectorHeight{Lines: n}, nil
+}
+
+// Inline reports whether the selector is drawn below the prompt instead of full screen
+func (h SelectorHeight) Inline() bool {
+	return h.Lines > 0 || h.Percent > 0
+}
+
+// lines returns the selector height on a terminal this many lines high
+func (h SelectorHeight) lines(terminalHeight int) int {
+	lines := h.Lines
+	if h.Percent > 0 {
+		lines = terminalHeight * h.Percent / 100
+	}
+	return min(max(lines, minInlineHeight), terminalHeight)
+}
+
+// newHelp returns the help line model, styled like the rest of the selector's hints
+func newHelp() help.Model {
+	h := help.New()
+	h.Styles.ShortKey = metadataStyle
+	h.Styles.ShortDesc = metadataStyle
+	h.Styles.ShortSeparator = metadataStyle
+	h.Styles.Ellipsis = metadataStyle
+	return h
+}
+
+// selectorProgramOptions returns the program options of a selector drawn on output:
+// full screen on the alt screen, or inline below the prompt with a fixed height
+func selectorProgramOptions(model tea.Model, inline SelectorHeight, output *os.File) []tea.ProgramOption {
+	var options []tea.ProgramOption
+	if output != os.Stdout {
+		options = append(options, tea.WithOutput(output))
+	}
+	if !inline.Inline() {
+		return append(options, tea.WithAltScreen())
+	}
+	// Size the list before the first frame so it doesn't jump
+	if width, height, err := term.GetSize(output.Fd()); err == nil {
+		model.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	}
+	return options
+}
+
 // ShowScriptSelectionWithFilter shows an interactive script selector with pre-populated filter
-func ShowScriptSelectionWithFilter(scoredScripts []ScoredScript, initialFilter string, db *Database, directory string) (*ScoredScript, error) {
-	if len(scoredScripts) == 0 {
+func ShowScriptSelectionWithFilter(params SelectorParams) (*SelectorResult, error) {
+	if len(params.Scripts) == 0 {
 		return nil, fmt.Errorf("no scripts available")
 	}
 
+	cfg := params.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	keys := DefaultKeyMap()
+	if params.Keys != nil {
+		keys = *params.Keys
+	}
+	useTerminalBackground(os.Stdout) // Before any styles are copied into the model
+
 	// Initialize text input for filter
 	ti := textinput.New()
 	ti.Placeholder = "Type to filter..."
@@ -513,7 +1450,7 @@ func ShowScriptSelectionWithFilter(scoredScripts []ScoredScript, initialFilter s
 	ti.Prompt = "/ "
 
 	// Set initial filter value
-	ti.SetValue(initialFilter)
+	ti.SetValue(params.InitialFilter)
 
 	// Initialize viewport with minimal size - will be resized when window size is detected
 	vp := viewport.New(initialViewportWidth, initialViewportHeight)
@@ -521,21 +1458,47 @@ func ShowScriptSelectionWithFilter(scoredScripts []ScoredScript, initialFilter s
 
 	// Create model (use pointer for tea.Model interface)
 	model := &filterableSelector{
-		filter:     ti,
-		viewport:   vp,
-		allScripts: scoredScripts,
-		selected:   0,
-		width:      0, // Will be set by WindowSizeMsg
-		height:     0, // Will be set by WindowSizeMsg
-		db:         db,
-		directory:  directory,
+		filter:          ti,
+		viewport:        vp,
+		allScripts:      params.Scripts,
+		selected:        0,
+		width:           0, // Will be set by WindowSizeMsg
+		height:          0, // Will be set by WindowSizeMsg
+		db:              params.DB,
+		directory:       params.Directory,
+		config:          cfg,
+		graph:           params.Graph,
+		collapsed:       make(map[string]bool),
+		groupBy:         normalizeGroupMode(cfg.GroupBy),
+		collapsedGroups: make(map[string]bool),
+		showPreview:     cfg.ShowPreview,
+		recentRuns:      make(map[string][]ScriptRun),
+		resultArgs:      params.Args,
+		suggestion:      params.Suggestion,
+		inputKeys:       keys.inputKeys(cfg.VimMode),
+		normalKeys:      keys.normalKeys(),
+		help:            newHelp(),
+	}
+	model.setTyping(!cfg.VimMode)
+	if !hasGroupMode(model.groupBy, params.Scripts) {
+		model.groupBy = GroupNone // A single "(root)" header says nothing
+	}
+
+	// Optionally start with every hook group collapsed
+	if cfg.HideLifecycleHooks {
+		for _, scored := range params.Scripts {
+			if scored.Script.HookOf != "" {
+				model.collapsed[hookParentKey(scored)] = true
+			}
+		}
 	}
 
 	// Apply initial filter
 	model.filterScripts()
 
-	// Run the program with alt screen to get full terminal dimensions
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	// Errors in the height were reported when the config was loaded
+	model.inline, _ = ParseSelectorHeight(cfg.Height)
+	p := tea.NewProgram(model, selectorProgramOptions(model, model.inline, os.Stdout)...)
 	finalModel, err := p.Run()
 	if err != nil {
 		return nil, err
@@ -543,7 +1506,7 @@ func ShowScriptSelectionWithFilter(scoredScripts []ScoredScript, initialFilter s
 
 	// Extract result
 	if m, ok := finalModel.(*filterableSelector); ok {
-		return m.result, nil
+		return &SelectorResult{Script: m.result, Args: m.resultArgs}, nil
 	}
 
 	return nil, fmt.Errorf("no script selected")
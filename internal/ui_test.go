@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestParseSelectorHeight(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected SelectorHeight
+		wantErr  bool
+	}{
+		{"", SelectorHeight{}, false},
+		{"full", SelectorHeight{}, false},
+		{"40%", SelectorHeight{Percent: 40}, false},
+		{" 15 ", SelectorHeight{Lines: 15}, false},
+		{"100%", SelectorHeight{Percent: 100}, false},
+		{"0", SelectorHeight{}, true},
+		{"120%", SelectorHeight{}, true},
+		{"half", SelectorHeight{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelectorHeight(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseSelectorHeight(%q) = %+v, %v, want %+v (error: %v)", tt.value, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestSelectorHeightLines(t *testing.T) {
+	tests := []struct {
+		height         SelectorHeight
+		terminalHeight int
+		expected       int
+	}{
+		{SelectorHeight{Percent: 40}, 50, 20},
+		{SelectorHeight{Percent: 40}, 20, minInlineHeight}, // Never too small for the list
+		{SelectorHeight{Lines: 30}, 50, 30},
+		{SelectorHeight{Lines: 30}, 24, 24}, // Never taller than the terminal
+	}
+
+	for _, tt := range tests {
+		if got := tt.height.lines(tt.terminalHeight); got != tt.expected {
+			t.Errorf("%+v.lines(%d) = %d, want %d", tt.height, tt.terminalHeight, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatScriptOptionTruncatesByDisplayWidth(t *testing.T) {
+	scored := ScoredScript{
+		Script:         NPMScript{Name: "déployer", Command: "echo 'déploiement terminé ✓ 部署完成 — prêt à servir les requêtes'", Source: "npm"},
+		CommandMatches: []int{0, 1, 40, 55}, // Partly past the cut
+	}
+
+	for width := 40; width <= 70; width++ {
+		option := FormatScriptOptionWithWidth(scored, width)
+		if !utf8.ValidString(option) {
+			t.Fatalf("width %d: truncation split a character: %q", width, option)
+		}
+		commandLine := strings.Split(option, "\n")[1]
+		if !strings.Contains(commandLine, "...") {
+			t.Errorf("width %d: expected the command to be truncated: %q", width, commandLine)
+		}
+		if got := lipgloss.Width(commandLine); got > width {
+			t.Errorf("width %d: command line is %d wide", width, got)
+		}
+	}
+}
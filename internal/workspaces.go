@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WorkspaceDirectories returns the workspace packages of a monorepo root, relative to
+// it with forward slashes: the "workspaces" of package.json (npm, yarn) and the
+// "packages" of pnpm-workspace.yaml, expanded and without "!" exclusions.
+func WorkspaceDirectories(root string) []string {
+	patterns := append(packageJSONWorkspaces(root), pnpmWorkspaces(root)...)
+
+	var include, exclude []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+		if excluded, ok := strings.CutPrefix(pattern, "!"); ok {
+			exclude = append(exclude, strings.TrimPrefix(excluded, "./"))
+		} else if pattern != "" {
+			include = append(include, pattern)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var directories []string
+	for _, pattern := range include {
+		for _, dir := range expandWorkspacePattern(root, pattern) {
+			if seen[dir] || dir == "." || matchesAnyWorkspace(dir, exclude) || !PackageJSONExists(filepath.Join(root, filepath.FromSlash(dir))) {
+				continue
+			}
+			seen[dir] = true
+			directories = append(directories, dir)
+		}
+	}
+	sort.Strings(directories)
+	return directories
+}
+
+// packageJSONWorkspaces reads "workspaces": ["apps/*"] or "workspaces": {"packages": [...]}
+func packageJSONWorkspaces(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+
+	var patterns []string
+	if json.Unmarshal(pkg.Workspaces, &patterns) == nil {
+		return patterns
+	}
+	var yarnStyle struct {
+		Packages []string `json:"packages"`
+	}
+	json.Unmarshal(pkg.Workspaces, &yarnStyle)
+	return yarnStyle.Packages
+}
+
+// pnpmWorkspaces reads the "packages:" list of pnpm-workspace.yaml. Only the list
+// form pnpm documents is understood, which avoids a YAML dependency.
+func pnpmWorkspaces(root string) []string {
+	file, err := os.Open(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []string
+	inPackages := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok && inPackages {
+			item, _, _ = strings.Cut(item, " #")
+			patterns = append(patterns, strings.Trim(strings.TrimSpace(item), `"'`))
+		}
+	}
+	return patterns
+}
+
+// expandWorkspacePattern lists the directories matching a workspace glob, where "**"
+// matches any depth ("packages/**") and node_modules is never searched
+func expandWorkspacePattern(root string, pattern string) []string {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(pattern, "**") {
+		matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		var directories []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				if rel, err := filepath.Rel(root, match); err == nil {
+					directories = append(directories, filepath.ToSlash(rel))
+				}
+			}
+		}
+		return directories
+	}
+
+	// Walk from the part before the first "**" and match the rest against each directory
+	base := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(pattern[:strings.Index(pattern, "**")], "/")))
+	var directories []string
+	filepath.WalkDir(base, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if name := entry.Name(); name == "node_modules" || strings.HasPrefix(name, ".") && p != base {
+			return filepath.SkipDir
+		}
+		if rel, err := filepath.Rel(root, p); err == nil && matchesWorkspace(filepath.ToSlash(rel), pattern) {
+			directories = append(directories, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return directories
+}
+
+// matchesWorkspace matches a relative directory against a workspace glob, "**"
+// standing for any number of path segments
+func matchesWorkspace(dir string, pattern string) bool {
+	patternParts := strings.Split(pattern, "/")
+	dirParts := strings.Split(dir, "/")
+
+	var match func(p, d int) bool
+	match = func(p, d int) bool {
+		if p == len(patternParts) {
+			return d == len(dirParts)
+		}
+		if patternParts[p] == "**" {
+			for skip := d; skip <= len(dirParts); skip++ {
+				if match(p+1, skip) {
+					return true
+				}
+			}
+			return false
+		}
+		if d == len(dirParts) {
+			return false
+		}
+		ok, _ := path.Match(patternParts[p], dirParts[d])
+		return ok && match(p+1, d+1)
+	}
+	return match(0, 0)
+}
+
+// matchesAnyWorkspace reports whether dir matches one of the patterns
+func matchesAnyWorkspace(dir string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesWorkspace(dir, strings.TrimSuffix(pattern, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadWorkspaceScripts reads the package.json scripts of every workspace package under
+// root. Names are prefixed with the workspace ("apps/web/dev"), so they don't share
+// history with the root's scripts, and File is relative to root. Packages without
+// scripts or with an unreadable package.json are skipped.
+func LoadWorkspaceScripts(root string) []NPMScript {
+	scripts, _ := loadWorkspaceScripts(root)
+	return scripts
+}
+
+// loadWorkspaceScripts is LoadWorkspaceScripts, also reporting whether every workspace
+// package.json could be read
+func loadWorkspaceScripts(root string) ([]NPMScript, bool) {
+	var scripts []NPMScript
+	ok := true
+	for _, workspace := range WorkspaceDirectories(root) {
+		pkg, err := ReadPackageJSON(filepath.Join(root, filepath.FromSlash(workspace)))
+		if err != nil {
+			ok = ok && errors.Is(err, ErrNoScripts)
+			continue
+		}
+		for _, script := range GetScripts(pkg) {
+			script.Name = workspace + "/" + script.Name
+			if script.HookOf != "" {
+				script.HookOf = workspace + "/" + script.HookOf
+			}
+			script.File = workspace + "/" + script.File
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, ok
+}
+
+// ScriptRunName returns the name a package manager knows a script by: the name
+// without its workspace prefix
+func ScriptRunName(script NPMScript) string {
+	if workspace := ScriptWorkspace(script); workspace != "" {
+		return strings.TrimPrefix(script.Name, workspace+"/")
+	}
+	return script.Name
+}
@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// createMonorepo writes a pnpm monorepo with package.json workspaces and
+// pnpm-workspace.yaml packages, returning its root
+func createMonorepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"package.json":                              `{"name": "acme", "private": true, "workspaces": ["apps/*", "!apps/legacy"]}`,
+		"pnpm-workspace.yaml":                       "packages:\n  - 'packages/**' # Shared libraries\n  - \"!**/fixtures/**\"\n",
+		"apps/web/package.json":                     `{"name": "web", "scripts": {"dev": "vite", "prebuild": "rimraf dist", "build": "vite build"}}`,
+		"apps/api/package.json":                     `{"name": "api", "scripts": {"dev": "tsx watch src"}}`,
+		"apps/legacy/package.json":                  `{"name": "legacy", "scripts": {"dev": "grunt"}}`,
+		"apps/docs/README.md":                       "No package here",
+		"packages/tools/cli/package.json":           `{"name": "cli", "scripts": {"test": "vitest"}}`,
+		"packages/ui/fixtures/package.json":         `{"name": "fixture", "scripts": {"test": "true"}}`,
+		"packages/ui/node_modules/dep/package.json": `{"name": "dep", "scripts": {"test": "true"}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestWorkspaceDirectories(t *testing.T) {
+	root := createMonorepo(t)
+
+	expected := []string{"apps/api", "apps/web", "packages/tools/cli"}
+	if got := WorkspaceDirectories(root); !reflect.DeepEqual(got, expected) {
+		t.Errorf("WorkspaceDirectories() = %v, want %v", got, expected)
+	}
+
+	if got := WorkspaceDirectories(t.TempDir()); len(got) != 0 {
+		t.Errorf("expected no workspaces outside a monorepo, got %v", got)
+	}
+}
+
+func TestLoadScriptsWorkspaces(t *testing.T) {
+	root := createMonorepo(t)
+
+	// Workspaces are only listed when enabled, and the root has no scripts of its own
+	if _, _, err := LoadScripts(root, ScriptSources{PackageJSON: true, PackageManager: "pnpm"}); !errors.Is(err, ErrNoScripts) {
+		t.Errorf("expected no scripts without workspaces, got %v", err)
+	}
+	scripts, _, err := LoadScripts(root, ScriptSources{PackageJSON: true, PackageManager: "pnpm", Workspaces: true})
+	if err != nil {
+		t.Fatalf("LoadScripts() error = %v", err)
+	}
+
+	byName := make(map[string]NPMScript)
+	var names []string
+	for _, script := range scripts {
+		byName[script.Name] = script
+		names = append(names, script.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"apps/api/dev", "apps/web/build", "apps/web/dev", "apps/web/prebuild", "packages/tools/cli/test"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("LoadScripts() names = %v, want %v", names, expected)
+	}
+
+	dev := byName["apps/web/dev"]
+	if dev.File != "apps/web/package.json" || dev.Source != "pnpm" || dev.Line != 1 {
+		t.Errorf("unexpected workspace script: %+v", dev)
+	}
+	if ScriptWorkspace(dev) != "apps/web" || ScriptRunName(dev) != "dev" {
+		t.Errorf("expected workspace apps/web and run name dev, got %q and %q", ScriptWorkspace(dev), ScriptRunName(dev))
+	}
+	if hook := byName["apps/web/prebuild"]; hook.HookOf != "apps/web/build" {
+		t.Errorf("expected prebuild to be a hook of apps/web/build, got %q", hook.HookOf)
+	}
+
+	// ws: filters on the loaded workspaces
+	var found []string
+	for _, scored := range SearchScripts(ScoreScripts(scripts, nil), "ws:web") {
+		found = append(found, scored.Script.Name)
+	}
+	sort.Strings(found)
+	if !reflect.DeepEqual(found, []string{"apps/web/build", "apps/web/dev", "apps/web/prebuild"}) {
+		t.Errorf("ws:web found %v", found)
+	}
+}
+
+func TestMatchesWorkspace(t *testing.T) {
+	tests := []struct {
+		dir, pattern string
+		expected     bool
+	}{
+		{"apps/web", "apps/*", true},
+		{"apps/web/nested", "apps/*", false},
+		{"packages/tools/cli", "packages/**", true},
+		{"packages/ui/fixtures", "**/fixtures/**", true},
+		{"packages/ui/fixtures/a", "**/fixtures/**", true},
+		{"libs/core", "packages/**", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesWorkspace(tt.dir, tt.pattern); got != tt.expected {
+			t.Errorf("matchesWorkspace(%q, %q) = %v, want %v", tt.dir, tt.pattern, got, tt.expected)
+		}
+	}
+}
+
+func TestGroupLoadedScriptsByWorkspace(t *testing.T) {
+	root := createMonorepo(t)
+	if err := os.WriteFile(filepath.Join(root, "package.json"), []byte(`{"name": "acme", "workspaces": ["apps/*", "!apps/legacy"], "scripts": {"lint": "eslint ."}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scripts, _, err := LoadScripts(root, ScriptSources{PackageJSON: true, PackageManager: "pnpm", Workspaces: true})
+	if err != nil {
+		t.Fatalf("LoadScripts() error = %v", err)
+	}
+	scored := ScoreScripts(scripts, nil)
+	sort.Slice(scored, func(i, j int) bool { return scored[i].Script.Name < scored[j].Script.Name })
+
+	_, _, groups := groupScripts(scored, nil, GroupWorkspace)
+	var sections []string
+	for i, group := range groups {
+		if i == 0 || groups[i-1] != group {
+			sections = append(sections, group)
+		}
+	}
+	expected := []string{"apps/api", "apps/web", rootWorkspaceGroup, "packages/tools/cli"} // In order of their first script
+	if !reflect.DeepEqual(sections, expected) {
+		t.Errorf("workspace sections = %v, want %v", sections, expected)
+	}
+	if got := nextGroupMode(GroupSource, scored); got != GroupWorkspace {
+		t.Errorf("nextGroupMode(source) = %s, want %s", got, GroupWorkspace)
+	}
+
+	// Without workspaces there is nothing to group by
+	single, _, err := LoadScripts(filepath.Join(root, "apps", "web"), ScriptSources{PackageJSON: true, PackageManager: "pnpm"})
+	if err != nil {
+		t.Fatalf("LoadScripts() error = %v", err)
+	}
+	if got := nextGroupMode(GroupSource, ScoreScripts(single, nil)); got != GroupPrefix {
+		t.Errorf("nextGroupMode(source) without workspaces = %s, want %s", got, GroupPrefix)
+	}
+}